@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+
+	igit "github.com/coreeng/semver-utils/pkg/git"
+	"github.com/spf13/cobra"
+)
+
+// addAuthFlags registers the flags configuring authentication against remotes. Secrets are only
+// read from environment variables, see igit.AuthSecretsFromEnv.
+func addAuthFlags(cmd *cobra.Command) {
+	cmd.Flags().String("auth", igit.AuthAuto, "Remote authentication: auto, none, ssh-agent, ssh-key, basic, token or credential-helper")
+	cmd.Flags().String("ssh-user", "", "User for SSH remotes (default: the user in the remote URL, or git)")
+	cmd.Flags().String("ssh-key", "", "Path of the private key file for SSH remotes (passphrase from "+igit.EnvSSHKeyPassphrase+")")
+	cmd.Flags().String("username", "", "User name for HTTP(S) remotes (password from "+igit.EnvPassword+", token from "+igit.EnvToken+")")
+}
+
+// authOptionsFromFlags reads the flags registered by addAuthFlags and the secrets from the environment.
+func authOptionsFromFlags(cmd *cobra.Command) igit.AuthOptions {
+	var opts igit.AuthOptions
+	opts.Method, _ = cmd.Flags().GetString("auth")
+	opts.SSHUser, _ = cmd.Flags().GetString("ssh-user")
+	opts.SSHKeyPath, _ = cmd.Flags().GetString("ssh-key")
+	opts.Username, _ = cmd.Flags().GetString("username")
+	return igit.AuthSecretsFromEnv(opts, os.Getenv)
+}
+
+// redactSecrets removes the authentication and signing secrets from the environment and any password
+// embedded in a URL from a message.
+func redactSecrets(msg string) string {
+	secrets := igit.AuthSecretsFromEnv(igit.AuthOptions{}, os.Getenv).Secrets()
+	secrets = append(secrets, igit.SigningSecretsFromEnv(igit.SigningKeyOptions{}, os.Getenv).Secrets()...)
+	return igit.RedactSecrets(msg, secrets...)
+}
+
+// redactValues redacts the secrets from every string in a result decoded by normalizeOutput.
+func redactValues(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return redactSecrets(v)
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = redactValues(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValues(item)
+		}
+	}
+	return value
+}
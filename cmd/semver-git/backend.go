@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+
+	igit "github.com/coreeng/semver-utils/pkg/git"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/spf13/cobra"
+)
+
+// addBackendFlag registers the --backend flag.
+func addBackendFlag(cmd *cobra.Command) {
+	cmd.Flags().String("backend", igit.BackendGoGit, "Git implementation that resolves commits and looks up, creates and pushes version tags: go-git (built in) or git (the git binary, for partial clones and credential helpers)")
+}
+
+// openBackend returns the named git backend for the repository. It must be closed after use.
+func openBackend(repository *git.Repository, name string) (igit.Backend, error) {
+	b, err := igit.NewBackend(repository, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open git backend: %v", err)
+	}
+	return b, nil
+}
+
+// fetchCommitObject returns the commit a revision such as HEAD, a branch, tag or commit hash points to,
+// like igit.FetchCommitObject with b.
+func fetchCommitObject(b igit.Backend, gitRef string) (*object.Commit, error) {
+	hash, err := b.ResolveRevision(gitRef)
+	if err != nil {
+		return nil, err
+	}
+	return b.Commit(hash)
+}
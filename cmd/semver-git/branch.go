@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	igit "github.com/coreeng/semver-utils/pkg/git"
+	"github.com/coreeng/semver-utils/pkg/semver"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// branchRule is the prerelease label and increment type --branch-strategy uses for the branches
+// matching pattern. An empty incrementType keeps the configured one.
+type branchRule struct {
+	pattern       string
+	prerelease    string
+	incrementType string
+}
+
+// defaultBranchRules are checked in order; the last rule matches every branch.
+var defaultBranchRules = []branchRule{
+	{pattern: "main"},
+	{pattern: "master"},
+	{pattern: "release/*", prerelease: "rc.{commits}", incrementType: "patch"},
+	{pattern: "hotfix/*", prerelease: "{branch}.{commits}", incrementType: "patch"},
+	{pattern: "feature/*", prerelease: "{branch}.{commits}", incrementType: "minor"},
+	{pattern: "*", prerelease: "{branch}.{commits}", incrementType: "patch"},
+}
+
+// parseBranchRules parses repeated <pattern>=<increment-type>:<prerelease> flag values. Either side
+// of the ':' may be empty, e.g. "develop=minor:dev.{commits}" or "main=:".
+func parseBranchRules(values []string) ([]branchRule, error) {
+	rules := make([]branchRule, 0, len(values))
+	for _, value := range values {
+		pattern, spec, ok := strings.Cut(value, "=")
+		incrementType, prerelease, hasColon := strings.Cut(spec, ":")
+		if !ok || !hasColon || pattern == "" {
+			return nil, fmt.Errorf("invalid branch rule '%s': expected <pattern>=<increment-type>:<prerelease>", value)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid branch rule '%s': %v", value, err)
+		}
+		incrementType = strings.ToLower(incrementType)
+		switch incrementType {
+		case "", "major", "minor", "patch":
+		default:
+			return nil, fmt.Errorf("invalid branch rule '%s': increment type must be 'major', 'minor', 'patch' or empty", value)
+		}
+		rules = append(rules, branchRule{pattern: pattern, prerelease: prerelease, incrementType: incrementType})
+	}
+	return rules, nil
+}
+
+// matchBranchRule returns the first of rules, followed by defaultBranchRules, matching branch.
+func matchBranchRule(rules []branchRule, branch string) branchRule {
+	for _, rule := range append(rules, defaultBranchRules...) {
+		if ok, _ := path.Match(rule.pattern, branch); ok {
+			return rule
+		}
+	}
+	return defaultBranchRules[len(defaultBranchRules)-1]
+}
+
+// expandPreRelease replaces {branch} with the sanitised branch name and {commits} with the number of
+// commits between prevCommit (nil if there is no previous version) and commit.
+func expandPreRelease(template, branch string, prevCommit, commit *object.Commit) (string, error) {
+	result := template
+	if strings.Contains(result, "{branch}") {
+		identifier := semver.SanitizeIdentifier(branch)
+		if identifier == "" {
+			return "", errors.New("prerelease uses {branch} but no branch was detected: check out a branch or set --branch")
+		}
+		result = strings.ReplaceAll(result, "{branch}", identifier)
+	}
+	if strings.Contains(result, "{commits}") {
+		commits, err := igit.CommitsSince(prevCommit, commit)
+		if err != nil {
+			return "", fmt.Errorf("failed to count commits: %v", err)
+		}
+		result = strings.ReplaceAll(result, "{commits}", strconv.Itoa(commits))
+	}
+	return result, nil
+}
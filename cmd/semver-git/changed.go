@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	igit "github.com/coreeng/semver-utils/pkg/git"
+	"github.com/spf13/cobra"
+)
+
+// parsePathMappings parses repeated <prefix>=<path> flag values into a map of prefix to path patterns.
+// An empty prefix (e.g. "=src") refers to the tags without a prefix.
+func parsePathMappings(values []string) (map[string][]string, error) {
+	paths := make(map[string][]string)
+	for _, value := range values {
+		prefix, pattern, ok := strings.Cut(value, "=")
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("invalid path mapping '%s': expected <prefix>=<path>", value)
+		}
+		paths[prefix] = append(paths[prefix], pattern)
+	}
+	return paths, nil
+}
+
+// changed command: reports, for every prefix mapped with --path, whether files under its paths
+// changed between its latest version tag and the given commit.
+var changedCmd = &cobra.Command{
+	Use:   "changed",
+	Short: "Report which components have changes since their latest version tag",
+	Run: func(cmd *cobra.Command, args []string) {
+		repoPath, _ := cmd.Flags().GetString("repo")
+		commitRef, _ := cmd.Flags().GetString("commit")
+		pathValues, _ := cmd.Flags().GetStringArray("path")
+		format := tagFormatOrExit(cmd)
+
+		paths, err := parsePathMappings(pathValues)
+		if err != nil {
+			exitWithUsageError(err)
+		}
+		if len(paths) == 0 {
+			exitWithUsageError(errors.New("at least one --path mapping must be specified"))
+		}
+
+		repository := openRepositoryOrExit(repoPath)
+
+		commit, err := igit.FetchCommitObject(repository, commitRef)
+		if err != nil {
+			exitWithUsageError(fmt.Errorf("failed to fetch commit object: %v", err))
+		}
+
+		changes, err := igit.DetectChanges(repository, commit, format, paths)
+		if err != nil {
+			outputErrorAndExit(fmt.Sprintf("failed to detect changes: %v", err))
+		}
+
+		result := make(map[string]interface{}, len(changes))
+		for prefix, change := range changes {
+			entry := map[string]interface{}{
+				"changed": change.Changed(),
+				"files":   change.Files,
+			}
+			if change.Tag != "" {
+				entry["tag"] = change.Tag
+				entry["version"] = change.Version.String()
+				entry["commit"] = change.Commit.Hash.String()
+			}
+			result[prefix] = entry
+		}
+		writeOutput(result)
+	},
+}
+
+func init() {
+	changedCmd.Flags().String("repo", ".", "Path to the Git repository or any directory inside it")
+	changedCmd.Flags().String("commit", "HEAD", "Git reference (commit hash, branch, tag, etc.)")
+	changedCmd.Flags().String("tag-format", igit.DefaultTagFormat.String(), "Tag name template with {prefix} and {version} placeholders, or a preset: default, no-v")
+	changedCmd.Flags().StringArray("path", nil, "Map a tag prefix to a directory or glob as <prefix>=<path> (repeatable)")
+
+	rootCmd.AddCommand(changedCmd)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+
+	igit "github.com/coreeng/semver-utils/pkg/git"
+	"github.com/spf13/cobra"
+)
+
+// components command: lists every tag prefix in the repository with its latest version.
+// The output is a JSON map keyed by prefix; tags without a prefix are reported under "".
+var componentsCmd = &cobra.Command{
+	Use:   "components",
+	Short: "List every version tag prefix in the repository with its latest version",
+	Run: func(cmd *cobra.Command, args []string) {
+		repoPath, _ := cmd.Flags().GetString("repo")
+		format := tagFormatOrExit(cmd)
+
+		repository := openRepositoryOrExit(repoPath)
+
+		components, err := igit.FetchComponentVersions(igit.NewGoGitBackend(repository), format)
+		if err != nil {
+			outputErrorAndExit(fmt.Sprintf("failed to fetch component versions: %v", err))
+		}
+
+		result := make(map[string]map[string]string, len(components))
+		for prefix, component := range components {
+			result[prefix] = map[string]string{
+				"tag":     component.Tag,
+				"version": component.Version.String(),
+				"commit":  component.Commit.Hash.String(),
+			}
+		}
+		writeOutput(result)
+	},
+}
+
+func init() {
+	componentsCmd.Flags().String("repo", ".", "Path to the Git repository or any directory inside it")
+	componentsCmd.Flags().String("tag-format", igit.DefaultTagFormat.String(), "Tag name template with {prefix} and {version} placeholders, or a preset: default, no-v")
+
+	rootCmd.AddCommand(componentsCmd)
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/coreeng/semver-utils/internal/config"
+	igit "github.com/coreeng/semver-utils/pkg/git"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// effectiveSettings holds the effective value and source of every flag of the running command.
+var effectiveSettings map[string]config.Value
+
+// isDefaultSetting reports whether a flag of the running command still has its built-in default,
+// i.e. it was not set on the command line, in the environment or in the repository configuration.
+func isDefaultSetting(name string) bool {
+	value, ok := effectiveSettings[name]
+	return !ok || value.Source == config.SourceDefault
+}
+
+// commandLineOnlyFlags are the flags that confirm destructive operations. They are never taken from
+// the environment or the repository configuration, so the confirmation is given for each run.
+var commandLineOnlyFlags = map[string]bool{"yes": true}
+
+// applyDefaults fills in every flag that was not given on the command line, first from its
+// SEMVER_GIT_* environment variable and then from the repository configuration, so that the
+// precedence is flag > env var > git config > config file > built-in default.
+// It returns the effective value and source of every flag.
+func applyDefaults(flags *pflag.FlagSet) (map[string]config.Value, error) {
+	effective := make(map[string]config.Value)
+
+	var setErr error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Name == "help" || setErr != nil {
+			return
+		}
+		if f.Changed {
+			effective[f.Name] = config.Value{Values: flagValues(f), Source: config.SourceFlag}
+			return
+		}
+		if commandLineOnlyFlags[f.Name] {
+			effective[f.Name] = config.Value{Values: flagValues(f), Source: config.SourceDefault}
+			return
+		}
+		if env, ok := os.LookupEnv(config.EnvName(f.Name)); ok {
+			values := []string{env}
+			if _, isSlice := f.Value.(pflag.SliceValue); isSlice {
+				values = strings.Split(env, ",")
+			}
+			if err := setFlagValues(f, values); err != nil {
+				setErr = fmt.Errorf("invalid value for %s: %w", config.EnvName(f.Name), err)
+				return
+			}
+			effective[f.Name] = config.Value{Values: values, Source: config.SourceEnv}
+		}
+	})
+	if setErr != nil {
+		return nil, setErr
+	}
+
+	settings, err := loadSettings(flags)
+	if err != nil {
+		return nil, err
+	}
+
+	flags.VisitAll(func(f *pflag.Flag) {
+		if _, ok := effective[f.Name]; ok || f.Name == "help" || setErr != nil {
+			return
+		}
+		if value, ok := settings[f.Name]; ok && f.Name != "repo" {
+			if err := setFlagValues(f, value.Values); err != nil {
+				setErr = fmt.Errorf("invalid %s value for '%s': %w", value.Source, f.Name, err)
+				return
+			}
+			effective[f.Name] = value
+			return
+		}
+		effective[f.Name] = config.Value{Values: flagValues(f), Source: config.SourceDefault}
+	})
+	if setErr != nil {
+		return nil, setErr
+	}
+
+	return effective, nil
+}
+
+// loadSettings resolves the repository configuration for the prefix and branch in flags. The prefix
+// itself may come from the configuration, so it is resolved first without a prefix.
+// If the repository cannot be opened no configuration is applied and the command reports the error itself.
+func loadSettings(flags *pflag.FlagSet) (map[string]config.Value, error) {
+	repoPath := "."
+	if f := flags.Lookup("repo"); f != nil {
+		repoPath = f.Value.String()
+	}
+	repository, err := igit.OpenRepository(repoPath)
+	if err != nil {
+		return nil, nil
+	}
+
+	layered, err := config.Load(repository)
+	if err != nil {
+		return nil, err
+	}
+
+	branch := ""
+	if f := flags.Lookup("branch"); f != nil {
+		branch = f.Value.String()
+	}
+	if branch == "" {
+		branch, _ = igit.DetectBranch(repository)
+	}
+
+	prefix := ""
+	if f := flags.Lookup("prefix"); f != nil {
+		prefix = f.Value.String()
+		if !f.Changed && os.Getenv(config.EnvName("prefix")) == "" {
+			if value, ok := layered.Resolve("", branch)["prefix"]; ok && len(value.Values) > 0 {
+				prefix = value.Values[len(value.Values)-1]
+			}
+		}
+	}
+
+	return layered.Resolve(prefix, branch), nil
+}
+
+// flagValues returns the current value of a flag as a list of strings.
+func flagValues(f *pflag.Flag) []string {
+	if slice, ok := f.Value.(pflag.SliceValue); ok {
+		return slice.GetSlice()
+	}
+	return []string{f.Value.String()}
+}
+
+// setFlagValues sets a flag without marking it as changed on the command line.
+// Scalar flags take the last of multiple values.
+func setFlagValues(f *pflag.Flag, values []string) error {
+	if slice, ok := f.Value.(pflag.SliceValue); ok {
+		return slice.Replace(values)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return f.Value.Set(values[len(values)-1])
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the semver-git configuration",
+	Run: func(cmd *cobra.Command, args []string) {
+		_ = cmd.Help()
+	},
+}
+
+// config show command: prints the effective value and source of every setting, as the other
+// commands would see them for the given prefix and branch.
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective settings and where they come from",
+	Run: func(cmd *cobra.Command, args []string) {
+		var target *cobra.Command
+		if name, _ := cmd.Flags().GetString("command"); name != "" {
+			c, rest, err := rootCmd.Find(strings.Fields(name))
+			if err != nil || c == rootCmd || len(rest) > 0 {
+				exitWithUsageError(fmt.Errorf("unknown command '%s'", name))
+			}
+			target = c
+		}
+
+		// The repository, prefix and branch come from config show. Without --command the flags of
+		// every command are collected, so all settings can be shown at once; a flag defined by several
+		// commands takes its default from the first one.
+		flags := pflag.NewFlagSet("settings", pflag.ContinueOnError)
+		for _, name := range []string{"repo", "prefix", "branch"} {
+			flags.AddFlag(cmd.Flags().Lookup(name))
+		}
+		if target != nil {
+			flags.AddFlagSet(target.Flags())
+			flags.AddFlagSet(target.InheritedFlags())
+		} else {
+			flags.AddFlagSet(rootCmd.PersistentFlags())
+			for _, c := range rootCmd.Commands() {
+				flags.AddFlagSet(c.Flags())
+			}
+		}
+
+		effective, err := applyDefaults(flags)
+		if err != nil {
+			exitWithUsageError(err)
+		}
+
+		names := make([]string, 0, len(effective))
+		for name := range effective {
+			if target != nil && target.Flags().Lookup(name) == nil && target.InheritedFlags().Lookup(name) == nil {
+				continue
+			}
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		result := make(map[string]interface{}, len(names))
+		for _, name := range names {
+			value := effective[name]
+			entry := map[string]interface{}{"source": value.Source}
+			if _, isSlice := flags.Lookup(name).Value.(pflag.SliceValue); isSlice {
+				entry["value"] = value.Values
+			} else if len(value.Values) > 0 {
+				entry["value"] = value.Values[len(value.Values)-1]
+			} else {
+				entry["value"] = ""
+			}
+			result[name] = entry
+		}
+		writeOutput(result)
+	},
+}
+
+func init() {
+	configShowCmd.Flags().String("repo", ".", "Path to the Git repository or any directory inside it")
+	configShowCmd.Flags().String("prefix", "", "Show the settings that apply to this tag prefix")
+	configShowCmd.Flags().String("branch", "", "Show the settings that apply to this branch (defaults to the current branch)")
+	configShowCmd.Flags().String("command", "", "Only show the settings of this command, e.g. create-tag")
+
+	// Every command reads its defaults from the environment and the repository configuration.
+	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		if cmd != configShowCmd {
+			effective, err := applyDefaults(cmd.Flags())
+			if err != nil {
+				exitWithUsageError(err)
+			}
+			effectiveSettings = effective
+		}
+		if err := setOutputFormat(cmd); err != nil {
+			exitWithUsageError(err)
+		}
+	}
+
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,768 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	igit "github.com/coreeng/semver-utils/pkg/git"
+	"github.com/coreeng/semver-utils/pkg/semver"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/spf13/cobra"
+)
+
+// tagPlan is the version tag computed by create-tag for a commit. It is printed by --dry-run,
+// written to a file by the plan command and executed by the apply command.
+type tagPlan struct {
+	Commit          string   `json:"commit"`
+	Prefix          string   `json:"prefix"`
+	TagFormat       string   `json:"tagFormat"`
+	PreviousTag     string   `json:"previousTag,omitempty"`
+	PreviousVersion string   `json:"previousVersion,omitempty"`
+	PreviousSigner  string   `json:"previousSigner,omitempty"`
+	Branch          string   `json:"branch,omitempty"`
+	IncrementType   string   `json:"incrementType,omitempty"`
+	Tag             string   `json:"tag"`
+	Version         string   `json:"version"`
+	Annotated       bool     `json:"annotated,omitempty"`
+	Signed          bool     `json:"signed,omitempty"`
+	Message         string   `json:"message,omitempty"`
+	Push            bool     `json:"push,omitempty"`
+	Upstream        string   `json:"upstream,omitempty"`
+	Skipped         bool     `json:"skipped,omitempty"`
+	SkipReason      string   `json:"skipReason,omitempty"`
+	FetchedTags     []string `json:"fetchedTags,omitempty"`
+	UpdatedTags     []string `json:"updatedTags,omitempty"`
+	ConflictingTags []string `json:"conflictingTags,omitempty"`
+	RepoState       string   `json:"repoState"`
+	RepoRoot        string   `json:"repoRoot,omitempty"`
+	DryRun          bool     `json:"dryRun,omitempty"`
+	Shallow         bool     `json:"shallow,omitempty"`
+}
+
+// createTagOptions holds the flags shared by create-tag, plan and describe.
+type createTagOptions struct {
+	commitRef            string
+	prefix               string
+	format               igit.TagFormat
+	incrementType        string
+	incrementTypeSet     bool
+	annotated            bool
+	sign                 bool
+	messageTemplate      string
+	prerelease           string
+	prereleaseSet        bool
+	prereleaseCounter    bool
+	branch               string
+	branchStrategy       bool
+	branchRules          []branchRule
+	buildMetadata        string
+	push                 bool
+	upstream             string
+	fetch                bool
+	overwriteTags        bool
+	dryRun               bool
+	auth                 igit.AuthOptions
+	shallow              shallowOptions
+	tagIndex             bool
+	backend              string
+	verify               *igit.SignatureVerification
+	createInitialVersion bool
+	initialVersion       string
+	onlyIfChanged        bool
+	paths                map[string][]string
+	ifUntagged           bool
+	taggedPolicy         string
+}
+
+// Reasons for skipping tag creation.
+const (
+	skipReasonUnchanged     = "unchanged"
+	skipReasonAlreadyTagged = "already-tagged"
+)
+
+// Policies for --if-untagged when the commit already has a version tag.
+const (
+	taggedPolicyReuse = "reuse"
+	taggedPolicyFail  = "fail"
+)
+
+// addVersionFlags registers the flags that determine the next version, shared by create-tag, plan and describe.
+func addVersionFlags(cmd *cobra.Command) {
+	cmd.Flags().String("repo", ".", "Path to the Git repository or any directory inside it")
+	cmd.Flags().String("commit", "HEAD", "Git reference (commit hash, branch, tag, etc.)")
+	cmd.Flags().String("prefix", "", "If set, the tag created (and the previous version searched for) will be formatted as <prefix>/v<semver> (see --tag-format)")
+	cmd.Flags().String("tag-format", igit.DefaultTagFormat.String(), "Tag name template with {prefix} and {version} placeholders, or a preset: default, no-v")
+	cmd.Flags().String("increment-type", "patch", "Version increment type: major, minor, or patch")
+	cmd.Flags().String("prerelease", "", "Set the prerelease identifier for the new version (optional); {branch} and {commits} are replaced by the sanitised branch name and the number of commits since the previous tag")
+	cmd.Flags().Bool("prerelease-counter", false, "Append a counter to the prerelease (e.g. rc.1, rc.2) one higher than the existing tags of the same version, and keep the version of a previous prerelease tag instead of bumping it again")
+	cmd.Flags().String("build-metadata", "", "Set the build metadata for the new version (optional)")
+	cmd.Flags().Bool("create-initial-version", false, "If true, create an initial version if no previous version tag is found (default is false)")
+	cmd.Flags().String("initial-version", "", "Specify the initial semantic version to use if no previous version tag is found (required if create-initial-version is true)")
+	cmd.Flags().String("branch", "", "Branch name used by --branch-strategy and {branch} (default: detected from HEAD or CI environment variables)")
+	cmd.Flags().Bool("branch-strategy", false, "Derive the prerelease label and increment type from the branch name unless set explicitly")
+	cmd.Flags().StringArray("branch-rule", nil, "Add a --branch-strategy rule as <pattern>=<increment-type>:<prerelease>, checked before the built-in rules (repeatable)")
+	addBackendFlag(cmd)
+}
+
+// addCreateTagFlags registers the flags shared by create-tag and plan.
+func addCreateTagFlags(cmd *cobra.Command) {
+	addVersionFlags(cmd)
+	cmd.Flags().Bool("annotated", false, "Create an annotated tag")
+	cmd.Flags().Bool("sign", false, "Create an annotated tag signed with the OpenPGP key given by --signing-key")
+	cmd.Flags().String("message-template", "", "Go text/template of the annotated tag message with .Tag, .Version, .Prefix, .PreviousTag, .PreviousVersion and .Commits (.Hash, .ShortHash, .Subject, .Author); implies --annotated")
+	cmd.Flags().Bool("push", false, "Push the new tag to a remote repository after creation? (default is false)")
+	cmd.Flags().String("upstream", "origin", "The remote to push the new tag to (default is 'origin')")
+	cmd.Flags().Bool("fetch", false, "Fetch the tags of the upstream remote before computing the new version")
+	cmd.Flags().Bool("overwrite-tags", false, "With --fetch, replace local tags that differ from the upstream remote instead of keeping them")
+	addShallowFlags(cmd)
+	addTagIndexFlag(cmd)
+	addAuthFlags(cmd)
+	addVerifyFlags(cmd)
+	cmd.Flags().StringArray("path", nil, "Map a tag prefix to a directory or glob as <prefix>=<path> (repeatable)")
+	cmd.Flags().Bool("only-if-changed", false, "Skip tag creation if no file under the --path mappings for --prefix changed since the previous tag")
+	cmd.Flags().Bool("if-untagged", false, "Only create a tag if the commit has no version tag for --prefix yet (see --tagged-policy)")
+	cmd.Flags().String("tagged-policy", taggedPolicyReuse, "With --if-untagged, what to do if the commit is already tagged: reuse (return the existing tag) or fail")
+}
+
+// createTagOptionsFromFlags reads the flags registered by addVersionFlags and addCreateTagFlags.
+// Flags a command does not have are left at their zero value.
+func createTagOptionsFromFlags(cmd *cobra.Command) (createTagOptions, error) {
+	var opts createTagOptions
+	opts.commitRef, _ = cmd.Flags().GetString("commit")
+	opts.prefix, _ = cmd.Flags().GetString("prefix")
+	opts.incrementType, _ = cmd.Flags().GetString("increment-type")
+	opts.incrementTypeSet = !isDefaultSetting("increment-type")
+	opts.annotated, _ = cmd.Flags().GetBool("annotated")
+	opts.sign, _ = cmd.Flags().GetBool("sign")
+	opts.messageTemplate, _ = cmd.Flags().GetString("message-template")
+	opts.prerelease, _ = cmd.Flags().GetString("prerelease")
+	opts.prereleaseSet = !isDefaultSetting("prerelease")
+	opts.prereleaseCounter, _ = cmd.Flags().GetBool("prerelease-counter")
+	opts.branch, _ = cmd.Flags().GetString("branch")
+	opts.branchStrategy, _ = cmd.Flags().GetBool("branch-strategy")
+	opts.buildMetadata, _ = cmd.Flags().GetString("build-metadata")
+	opts.push, _ = cmd.Flags().GetBool("push")
+	opts.upstream, _ = cmd.Flags().GetString("upstream")
+	opts.fetch, _ = cmd.Flags().GetBool("fetch")
+	opts.overwriteTags, _ = cmd.Flags().GetBool("overwrite-tags")
+	opts.auth = authOptionsFromFlags(cmd)
+	opts.createInitialVersion, _ = cmd.Flags().GetBool("create-initial-version")
+	opts.initialVersion, _ = cmd.Flags().GetString("initial-version")
+	opts.onlyIfChanged, _ = cmd.Flags().GetBool("only-if-changed")
+	opts.ifUntagged, _ = cmd.Flags().GetBool("if-untagged")
+	opts.taggedPolicy, _ = cmd.Flags().GetString("tagged-policy")
+	if opts.ifUntagged && opts.taggedPolicy != taggedPolicyReuse && opts.taggedPolicy != taggedPolicyFail {
+		return opts, fmt.Errorf("invalid tagged-policy '%s': must be '%s' or '%s'", opts.taggedPolicy, taggedPolicyReuse, taggedPolicyFail)
+	}
+
+	formatStr, _ := cmd.Flags().GetString("tag-format")
+	format, err := igit.ParseTagFormat(formatStr)
+	if err != nil {
+		return opts, err
+	}
+	opts.format = format
+
+	opts.verify, err = verificationFromFlags(cmd)
+	if err != nil {
+		return opts, err
+	}
+	opts.shallow, err = shallowOptionsFromFlags(cmd)
+	if err != nil {
+		return opts, err
+	}
+	opts.tagIndex, _ = cmd.Flags().GetBool("tag-index")
+	opts.backend, _ = cmd.Flags().GetString("backend")
+
+	ruleValues, _ := cmd.Flags().GetStringArray("branch-rule")
+	opts.branchRules, err = parseBranchRules(ruleValues)
+	if err != nil {
+		return opts, err
+	}
+
+	pathValues, _ := cmd.Flags().GetStringArray("path")
+	paths, err := parsePathMappings(pathValues)
+	if err != nil {
+		return opts, err
+	}
+	opts.paths = paths
+	if opts.onlyIfChanged && len(paths[opts.prefix]) == 0 {
+		return opts, fmt.Errorf("only-if-changed requires a --path mapping for prefix '%s'", opts.prefix)
+	}
+	return opts, nil
+}
+
+// computeTagPlan fetches the previous version tag, determines the new version and checks that
+// its tag can be created, without modifying the repository.
+func computeTagPlan(repository *git.Repository, opts createTagOptions) (*tagPlan, error) {
+	b, err := openBackend(repository, opts.backend)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = b.Close() }()
+
+	commit, err := fetchCommitObject(b, opts.commitRef)
+	if err != nil {
+		return nil, igit.WrapSentinel(errUsage, "failed to fetch commit object: %v", err)
+	}
+
+	// Fetch the remote tags first, so that versions already taken on the remote are not reused. A dry
+	// run only lists them, leaving the local tags as they are.
+	var fetched igit.FetchTagsResult
+	if opts.fetch && opts.dryRun {
+		fetched, err = listRemoteTags(repository, opts.upstream, opts.auth, opts.overwriteTags)
+		if err != nil {
+			return nil, err
+		}
+	} else if opts.fetch {
+		fetched, err = fetchRemoteTags(repository, opts.upstream, opts.auth, opts.overwriteTags)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	repoState, err := igit.TagsFingerprint(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository state: %v", err)
+	}
+
+	plan := &tagPlan{
+		Commit:          commit.Hash.String(),
+		Prefix:          opts.prefix,
+		TagFormat:       opts.format.String(),
+		Annotated:       opts.annotated || opts.sign || opts.messageTemplate != "",
+		Signed:          opts.sign,
+		Push:            opts.push,
+		Upstream:        opts.upstream,
+		FetchedTags:     fetched.New,
+		UpdatedTags:     fetched.Updated,
+		ConflictingTags: fetched.Conflicting,
+		RepoState:       repoState,
+		RepoRoot:        repositoryRoot(repository),
+	}
+
+	plan.Branch = opts.branch
+	if plan.Branch == "" {
+		plan.Branch, err = igit.DetectBranch(repository)
+		if err != nil {
+			return nil, fmt.Errorf("failed to detect branch: %v", err)
+		}
+	}
+	if opts.branchStrategy {
+		if plan.Branch == "" {
+			return nil, errors.New("branch-strategy requires a branch: check out a branch or set --branch")
+		}
+		rule := matchBranchRule(opts.branchRules, plan.Branch)
+		if !opts.prereleaseSet {
+			opts.prerelease = rule.prerelease
+		}
+		if !opts.incrementTypeSet && rule.incrementType != "" {
+			opts.incrementType = rule.incrementType
+		}
+	}
+
+	// Reuse (or refuse) an existing version tag on the commit, e.g. when a CI job is retried.
+	if opts.ifUntagged {
+		existingTag, existingVersion, _, _, err := fetchVersionTag(b, commit, opts.format, opts.prefix, true, false, opts.verify)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch version tag: %w", err)
+		}
+		if existingTag != "" {
+			if opts.taggedPolicy == taggedPolicyFail {
+				return nil, igit.WrapSentinel(igit.ErrTagExists, "commit %s is already tagged with %s", commit.Hash, existingTag)
+			}
+			plan.Tag = existingTag
+			plan.Version = existingVersion.String()
+			plan.Skipped = true
+			plan.SkipReason = skipReasonAlreadyTagged
+			return plan, nil
+		}
+	}
+
+	// Try to fetch a previous version tag. Prereleases of a branch rule are based on the last release,
+	// so that the prereleases of earlier builds and of other branches do not raise the version of
+	// every build, and {commits} keeps counting from the release.
+	releaseBase := opts.branchStrategy && opts.prerelease != ""
+	prevTag, currentVersion, prevCommit, prevSigner, err := fetchVersionTag(b, commit, opts.format, opts.prefix, false, releaseBase, opts.verify)
+	if err != nil {
+		// A previous tag that is not signed by a trusted key must not be used as the base version.
+		if errors.Is(err, igit.ErrTagNotSigned) || errors.Is(err, igit.ErrTagSignatureInvalid) {
+			return nil, fmt.Errorf("failed to verify previous version tag: %w", err)
+		}
+		// We ignore the error here as it's not critical for version bumping
+		prevTag = ""
+		currentVersion = semver.SemVer{}
+		prevCommit = nil
+	}
+	if prevTag != "" {
+		plan.PreviousTag = prevTag
+		plan.PreviousVersion = currentVersion.String()
+	}
+	if prevSigner != nil {
+		plan.PreviousSigner = igit.SignerIdentity(prevSigner)
+	}
+
+	// Skip tagging if none of the component's paths changed since the previous tag.
+	if opts.onlyIfChanged && prevTag != "" {
+		changedFiles, err := igit.ChangedFiles(prevCommit, commit, opts.paths[opts.prefix])
+		if err != nil {
+			return nil, fmt.Errorf("failed to detect changes: %v", err)
+		}
+		if len(changedFiles) == 0 {
+			plan.Commit = prevCommit.Hash.String()
+			plan.Tag = prevTag
+			plan.Version = currentVersion.String()
+			plan.Skipped = true
+			plan.SkipReason = skipReasonUnchanged
+			return plan, nil
+		}
+	}
+
+	var newVersion semver.SemVer
+	if prevTag != "" {
+		plan.IncrementType = strings.ToLower(opts.incrementType)
+		newVersion, err = bumpVersion(currentVersion, plan.IncrementType, opts.prereleaseCounter)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// In a shallow clone the previous tag may just not have been fetched.
+		if err := shallowError(repository, opts.prefix); err != nil {
+			return nil, err
+		}
+		// No previous tag found; create an initial version if allowed.
+		if !opts.createInitialVersion {
+			return nil, igit.WrapSentinel(igit.ErrNoVersionTag, "No previous version tag found and create-initial-version is false.")
+		}
+		if opts.initialVersion == "" {
+			return nil, errors.New("initial-version must be specified when create-initial-version is true")
+		}
+		plan.IncrementType = "initial"
+		newVersion, err = semver.Parse(opts.initialVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse initial version: %v", err)
+		}
+	}
+
+	// Apply prerelease and build metadata if provided.
+	if opts.prerelease != "" {
+		prerelease, err := expandPreRelease(opts.prerelease, plan.Branch, prevCommit, commit)
+		if err != nil {
+			return nil, err
+		}
+		if opts.prereleaseCounter {
+			counter, err := igit.NextPreReleaseCounter(b, opts.format, opts.prefix, newVersion, semver.PreRelease(prerelease))
+			if err != nil {
+				return nil, fmt.Errorf("failed to determine prerelease counter: %v", err)
+			}
+			prerelease = fmt.Sprintf("%s.%d", prerelease, counter)
+		}
+		newVersion, err = newVersion.SetPreRelease(semver.PreRelease(prerelease))
+		if err != nil {
+			return nil, fmt.Errorf("failed to set prerelease: %v", err)
+		}
+	}
+	if opts.buildMetadata != "" {
+		newVersion, err = newVersion.SetBuildMetadata(semver.BuildMetadata(opts.buildMetadata))
+		if err != nil {
+			return nil, fmt.Errorf("failed to set build metadata: %v", err)
+		}
+	}
+
+	if err := igit.CheckVersionTagAvailable(b, opts.format, opts.prefix, newVersion); err != nil {
+		return nil, fmt.Errorf("failed to create new tag: %w", err)
+	}
+	plan.Tag, err = opts.format.Format(opts.prefix, newVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new tag: %w", err)
+	}
+	plan.Version = newVersion.String()
+	// The remote tags listed by a dry run are not in the repository, but taken all the same.
+	for _, name := range append(fetched.New, fetched.Updated...) {
+		if version, ok, _ := opts.format.Match(name, opts.prefix); ok && version.Compare(newVersion) == 0 {
+			return nil, igit.WrapSentinel(igit.ErrTagExists, "failed to create new tag: tag %s collides with tag %s on remote %s", plan.Tag, name, opts.upstream)
+		}
+	}
+
+	if opts.messageTemplate != "" {
+		commits, err := igit.CommitLog(prevCommit, commit)
+		if err != nil {
+			return nil, err
+		}
+		plan.Message, err = igit.RenderTagMessage(opts.messageTemplate, igit.TagMessageData{
+			Tag:             plan.Tag,
+			Version:         plan.Version,
+			Prefix:          plan.Prefix,
+			PreviousTag:     plan.PreviousTag,
+			PreviousVersion: plan.PreviousVersion,
+			Commits:         igit.NewTagMessageCommits(commits),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return plan, nil
+}
+
+// bumpVersion increments the given part of version. With keepPreReleaseBase, a prerelease version is
+// treated as a preview of its release version, which is only bumped if it is lower than the requested
+// increment: 1.2.4-rc.1 becomes 1.2.4 for patch and 1.3.0 for minor, while 1.3.0-rc.1 becomes 1.3.0 for minor.
+func bumpVersion(version semver.SemVer, incrementType string, keepPreReleaseBase bool) (semver.SemVer, error) {
+	keepBase := keepPreReleaseBase && version.PreRelease != ""
+	switch incrementType {
+	case "major":
+		if keepBase && version.Minor == 0 && version.Patch == 0 {
+			return version.Release(), nil
+		}
+		return version.BumpMajor(), nil
+	case "minor":
+		if keepBase && version.Patch == 0 {
+			return version.Release(), nil
+		}
+		return version.BumpMinor(), nil
+	case "patch":
+		if keepBase {
+			return version.Release(), nil
+		}
+		return version.BumpPatch(), nil
+	default:
+		return semver.SemVer{}, errors.New("invalid increment type: must be 'major', 'minor', or 'patch'")
+	}
+}
+
+// executeOptions holds the flags that control how a tag plan is executed.
+type executeOptions struct {
+	auth               igit.AuthOptions
+	backend            string
+	signing            igit.SigningKeyOptions
+	tagger             taggerOptions
+	keepLocalOnFailure bool
+}
+
+// taggerOptions holds the flags that override the tagger of annotated tags.
+type taggerOptions struct {
+	name  string
+	email string
+	date  string
+}
+
+// resolveTagger returns the tagger of an annotated tag on commit. The name and email default to the
+// git config identity, or the committer of commit if none is configured; the date defaults to
+// SOURCE_DATE_EPOCH or now.
+func resolveTagger(repository *git.Repository, commit *object.Commit, opts taggerOptions) (*object.Signature, error) {
+	name, email, err := igit.ConfigIdentity(repository, os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" || email == "" {
+		name, email = commit.Committer.Name, commit.Committer.Email
+	}
+	if opts.name != "" {
+		name = opts.name
+	}
+	if opts.email != "" {
+		email = opts.email
+	}
+	date, err := igit.TagDate(opts.date, os.Getenv, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &object.Signature{Name: name, Email: email, When: date}, nil
+}
+
+// Steps of executing a tag plan and their status, as reported by tagTransactionError.
+const (
+	stepCreate = "create"
+	stepPush   = "push"
+
+	stepDone       = "done"
+	stepFailed     = "failed"
+	stepRolledBack = "rolled-back"
+	stepKept       = "kept"
+)
+
+// tagStep is a step of executing a tag plan.
+type tagStep struct {
+	Step   string `json:"step"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// tagTransactionError is returned by executeTagPlan if the tag was created but could not be pushed.
+// It lists the steps that ran and whether the local tag was rolled back.
+type tagTransactionError struct {
+	err        error
+	tag        string
+	steps      []tagStep
+	rolledBack bool
+}
+
+func (e *tagTransactionError) Error() string { return e.err.Error() }
+
+func (e *tagTransactionError) Unwrap() error { return e.err }
+
+func (e *tagTransactionError) details() map[string]interface{} {
+	return map[string]interface{}{
+		"tag":        e.tag,
+		"steps":      e.steps,
+		"rolledBack": e.rolledBack,
+	}
+}
+
+// executeTagPlan creates the planned tag and pushes it if requested. Creating and pushing is one unit:
+// if the push fails, the local tag is deleted again, unless keepLocalOnFailure is set and the remote
+// does not have a tag of the same name. It returns the create-tag response.
+func executeTagPlan(repository *git.Repository, plan *tagPlan, opts executeOptions) (map[string]interface{}, error) {
+	if plan.Skipped {
+		return map[string]interface{}{
+			"tag":        plan.Tag,
+			"version":    plan.Version,
+			"commit":     plan.Commit,
+			"created":    false,
+			"skipped":    true,
+			"skipReason": plan.SkipReason,
+			"repoRoot":   repositoryRoot(repository),
+		}, nil
+	}
+
+	b, err := openBackend(repository, opts.backend)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = b.Close() }()
+
+	commit, err := b.Commit(plumbing.NewHash(plan.Commit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch commit object: %v", err)
+	}
+	format, err := igit.ParseTagFormat(plan.TagFormat)
+	if err != nil {
+		return nil, err
+	}
+	newVersion, err := semver.Parse(plan.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse version: %v", err)
+	}
+
+	// Create the new version tag.
+	var tagOpts *igit.TagOptions
+	if plan.Annotated {
+		tagOpts = &igit.TagOptions{Message: plan.Message}
+		tagOpts.Tagger, err = resolveTagger(repository, commit, opts.tagger)
+		if err != nil {
+			return nil, err
+		}
+		if plan.Signed {
+			tagOpts.SignKey, err = igit.LoadSigningKey(opts.signing)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	created, err := igit.CreateVersionTagContext(context.Background(), nil, igit.CreateOptions{
+		Revision:   commit.Hash.String(),
+		Prefix:     plan.Prefix,
+		Format:     format,
+		Version:    newVersion,
+		Annotation: tagOpts,
+		Backend:    b,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new tag: %w", err)
+	}
+	newTag := created.Name
+
+	response := map[string]interface{}{
+		"tag":      newTag,
+		"version":  newVersion.String(),
+		"commit":   commit.Hash.String(),
+		"created":  true,
+		"repoRoot": repositoryRoot(repository),
+	}
+	if plan.Signed {
+		response["signed"] = true
+	}
+	if plan.PreviousSigner != "" {
+		response["previousTag"] = plan.PreviousTag
+		response["previousSigner"] = plan.PreviousSigner
+	}
+
+	// Push the tag to remote if requested.
+	if plan.Push {
+		if err := pushTag(repository, b, plan.Upstream, newTag, opts.auth); err != nil {
+			return nil, rollbackTag(b, newTag, err, opts.keepLocalOnFailure)
+		}
+		response["pushed"] = true
+		response["upstream"] = plan.Upstream
+	}
+
+	return response, nil
+}
+
+// pushTag pushes a tag to the upstream remote with b, authenticating with auth.
+func pushTag(repository *git.Repository, b igit.Backend, upstream, tag string, auth igit.AuthOptions) error {
+	remoteAuth, err := igit.RemoteAuth(repository, upstream, auth)
+	if err == nil {
+		err = b.PushTag(upstream, tag, remoteAuth)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to push tag %s to remote %s: %w", tag, upstream, err)
+	}
+	return nil
+}
+
+// rollbackTag deletes a tag that was created locally but could not be pushed, and returns a
+// tagTransactionError describing pushErr and the rollback. A tag taken on the remote by another job
+// is always deleted; otherwise keepLocal keeps it.
+func rollbackTag(b igit.Backend, tag string, pushErr error, keepLocal bool) error {
+	txErr := &tagTransactionError{
+		err: pushErr,
+		tag: tag,
+		steps: []tagStep{
+			{Step: stepCreate, Status: stepDone},
+			{Step: stepPush, Status: stepFailed, Error: pushErr.Error()},
+		},
+	}
+	if keepLocal && !errors.Is(pushErr, igit.ErrTagExistsOnRemote) {
+		txErr.steps[0].Status = stepKept
+		return txErr
+	}
+	if err := b.DeleteTag(tag); err != nil {
+		txErr.err = fmt.Errorf("%w; failed to delete local tag %s: %v", pushErr, tag, err)
+		return txErr
+	}
+	txErr.steps[0].Status = stepRolledBack
+	txErr.rolledBack = true
+	return txErr
+}
+
+// addExecuteFlags registers the flags read by executeOptionsFromFlags, other than the authentication flags.
+func addExecuteFlags(cmd *cobra.Command) {
+	cmd.Flags().String("signing-key", "", "Path of the armored or binary OpenPGP private key or keyring that signs tags (default: the armored key in "+igit.EnvSigningKeyArmored+"; passphrase from "+igit.EnvSigningKeyPassphrase+")")
+	cmd.Flags().String("signing-key-id", "", "Key ID, fingerprint or user ID of the signing key to use from a keyring with several keys")
+	cmd.Flags().String("tagger-name", "", "Tagger name of annotated tags (default: the git config identity)")
+	cmd.Flags().String("tagger-email", "", "Tagger email of annotated tags (default: the git config identity)")
+	cmd.Flags().String("tagger-date", "", "Tagger date of annotated tags as Unix seconds or RFC 3339 (default: "+igit.EnvSourceDateEpoch+" or now)")
+	cmd.Flags().Bool("keep-local-on-failure", false, "Keep the created local tag if pushing it fails (it is always deleted if the remote has a tag of the same name)")
+}
+
+// executeOptionsFromFlags reads the flags registered by addExecuteFlags and addAuthFlags, and the
+// signing secrets from the environment.
+func executeOptionsFromFlags(cmd *cobra.Command) executeOptions {
+	var signing igit.SigningKeyOptions
+	signing.Path, _ = cmd.Flags().GetString("signing-key")
+	signing.ID, _ = cmd.Flags().GetString("signing-key-id")
+	var tagger taggerOptions
+	tagger.name, _ = cmd.Flags().GetString("tagger-name")
+	tagger.email, _ = cmd.Flags().GetString("tagger-email")
+	tagger.date, _ = cmd.Flags().GetString("tagger-date")
+	keepLocal, _ := cmd.Flags().GetBool("keep-local-on-failure")
+	backend, _ := cmd.Flags().GetString("backend")
+	return executeOptions{
+		auth:               authOptionsFromFlags(cmd),
+		backend:            backend,
+		signing:            igit.SigningSecretsFromEnv(signing, os.Getenv),
+		tagger:             tagger,
+		keepLocalOnFailure: keepLocal,
+	}
+}
+
+// createTagWithRetry computes and executes the tag plan. If the push fails because a concurrent job
+// pushed the same version first, the local tag is removed, the remote tags are fetched and the
+// version is computed again, up to maxAttempts times in total.
+func createTagWithRetry(repository *git.Repository, opts createTagOptions, execOpts executeOptions, maxAttempts int) (map[string]interface{}, error) {
+	if maxAttempts < 1 {
+		return nil, fmt.Errorf("invalid max-attempts %d: must be at least 1", maxAttempts)
+	}
+	for attempt := 1; ; attempt++ {
+		plan, err := computeTagPlan(repository, opts)
+		if err != nil {
+			return nil, err
+		}
+		result, err := executeTagPlan(repository, plan, execOpts)
+		if errors.Is(err, igit.ErrTagExistsOnRemote) && attempt < maxAttempts {
+			opts.fetch = true
+			continue
+		}
+		if err != nil {
+			if errors.Is(err, igit.ErrTagExistsOnRemote) {
+				return nil, fmt.Errorf("%w (after %d attempts)", err, attempt)
+			}
+			return nil, err
+		}
+		if opts.fetch {
+			result["fetchedTags"] = plan.FetchedTags
+			result["updatedTags"] = plan.UpdatedTags
+			result["conflictingTags"] = plan.ConflictingTags
+		}
+		if plan.Push {
+			result["attempts"] = attempt
+		}
+		return result, nil
+	}
+}
+
+// create-tag command: calls FetchVersionTag first. If a previous tag is found,
+// it increments the desired version field and then calls CreateVersionTag.
+// If no previous tag is found and --create-initial-version=true, it uses the provided --initial-version.
+// With --dry-run, the computed tag is printed without modifying the repository or remotes.
+var createTagCmd = &cobra.Command{
+	Use:   "create-tag",
+	Short: "Create an incremented semantic version tag on the specified commit",
+	Run: func(cmd *cobra.Command, args []string) {
+		repoPath, _ := cmd.Flags().GetString("repo")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		opts, err := createTagOptionsFromFlags(cmd)
+		if err != nil {
+			exitWithUsageError(err)
+		}
+
+		repository := openRepositoryOrExit(repoPath)
+		// A dry run uses the history and the tag index as they are, without deepening or writing them.
+		if !dryRun {
+			repository, err = deepenIfShallow(repository, opts.commitRef, opts.format, opts.prefix, opts.upstream, opts.auth, opts.shallow)
+			if err != nil {
+				exitWithError(err)
+			}
+			if err := updateTagIndex(repository, opts.tagIndex); err != nil {
+				exitWithError(err)
+			}
+		}
+
+		var response interface{}
+		if dryRun {
+			opts.dryRun = true
+			plan, err := computeTagPlan(repository, opts)
+			if err != nil {
+				exitWithError(err)
+			}
+			plan.DryRun = true
+			plan.Shallow, _ = igit.IsShallow(repository)
+			response = plan
+		} else {
+			maxAttempts, _ := cmd.Flags().GetInt("max-attempts")
+			response, err = createTagWithRetry(repository, opts, executeOptionsFromFlags(cmd), maxAttempts)
+			if err != nil {
+				exitWithError(err)
+			}
+		}
+
+		writeOutput(response)
+	},
+}
+
+func init() {
+	addCreateTagFlags(createTagCmd)
+	createTagCmd.Flags().Bool("dry-run", false, "Compute and print the tag that would be created without modifying the repository or remotes")
+	addExecuteFlags(createTagCmd)
+	createTagCmd.Flags().Int("max-attempts", 3, "With --push, how many times to compute and push a new version if a concurrent job pushed the same version first")
+
+	rootCmd.AddCommand(createTagCmd)
+}
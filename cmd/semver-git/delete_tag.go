@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	igit "github.com/coreeng/semver-utils/pkg/git"
+	"github.com/coreeng/semver-utils/pkg/semver"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/spf13/cobra"
+)
+
+// deleteTagOptions holds the flags of the delete-tag command.
+type deleteTagOptions struct {
+	tags       []string
+	prefix     string
+	format     igit.TagFormat
+	versions   []string
+	constraint string
+	all        bool
+}
+
+// selectedTag is a version tag selected for deletion.
+type selectedTag struct {
+	Tag     string `json:"tag"`
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+}
+
+// selectTagsForDeletion returns the version tags matching opts, sorted by version. Tags given by name
+// must exist and be version tags in the configured format.
+func selectTagsForDeletion(repository *git.Repository, opts deleteTagOptions) ([]selectedTag, error) {
+	selected := make(map[string]semver.SemVer)
+	for _, tag := range opts.tags {
+		_, version, ok := opts.format.Parse(tag)
+		if !ok {
+			return nil, fmt.Errorf("refusing to delete tag '%s': not a version tag in the format %s", tag, opts.format)
+		}
+		if _, err := repository.Tag(tag); err != nil {
+			return nil, fmt.Errorf("tag '%s' not found", tag)
+		}
+		selected[tag] = version
+	}
+
+	if len(opts.versions) > 0 || opts.constraint != "" || opts.all {
+		var constraint *semver.Constraint
+		if opts.constraint != "" {
+			c, err := semver.ParseConstraint(opts.constraint)
+			if err != nil {
+				return nil, err
+			}
+			constraint = &c
+		}
+		wanted := make(map[string]bool, len(opts.versions))
+		for _, v := range opts.versions {
+			version, err := semver.Parse(v)
+			if err != nil {
+				return nil, err
+			}
+			wanted[version.String()] = true
+		}
+
+		versions, err := igit.ListVersionTags(igit.NewGoGitBackend(repository), opts.format, opts.prefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list version tags: %v", err)
+		}
+		for tag, version := range versions {
+			if len(wanted) > 0 && !wanted[version.String()] {
+				continue
+			}
+			if constraint != nil && !constraint.Check(version) {
+				continue
+			}
+			selected[tag] = version
+		}
+	}
+
+	result := make([]selectedTag, 0, len(selected))
+	for tag, version := range selected {
+		commit, err := igit.FetchCommitObject(repository, "refs/tags/"+tag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve tag '%s': %v", tag, err)
+		}
+		result = append(result, selectedTag{Tag: tag, Version: version.String(), Commit: commit.Hash.String()})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		vi, _ := semver.Parse(result[i].Version)
+		vj, _ := semver.Parse(result[j].Version)
+		if c := vi.Compare(vj); c != 0 {
+			return c < 0
+		}
+		return result[i].Tag < result[j].Tag
+	})
+	return result, nil
+}
+
+// deleteRemoteTags deletes the given tags from a remote in a single push, authenticating with auth.
+func deleteRemoteTags(repository *git.Repository, upstream string, tags []selectedTag, auth igit.AuthOptions) error {
+	remoteAuth, err := igit.RemoteAuth(repository, upstream, auth)
+	if err != nil {
+		return fmt.Errorf("failed to delete tags from remote %s: %v", upstream, err)
+	}
+	refSpecs := make([]config.RefSpec, 0, len(tags))
+	for _, tag := range tags {
+		refSpecs = append(refSpecs, config.RefSpec(":refs/tags/"+tag.Tag))
+	}
+	err = repository.Push(&git.PushOptions{RemoteName: upstream, RefSpecs: refSpecs, Auth: remoteAuth})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("failed to delete tags from remote %s: %v", upstream, err)
+	}
+	return nil
+}
+
+// delete-tag command: deletes version tags selected by name, version, constraint or prefix, locally
+// and optionally on the upstream remote. It only runs with --yes; --dry-run previews the selection.
+var deleteTagCmd = &cobra.Command{
+	Use:   "delete-tag [<tag>...]",
+	Short: "Delete semantic version tags locally and optionally on a remote",
+	Run: func(cmd *cobra.Command, args []string) {
+		repoPath, _ := cmd.Flags().GetString("repo")
+		remote, _ := cmd.Flags().GetBool("remote")
+		upstream, _ := cmd.Flags().GetString("upstream")
+		yes, _ := cmd.Flags().GetBool("yes")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		opts := deleteTagOptions{tags: args}
+		opts.prefix, _ = cmd.Flags().GetString("prefix")
+		opts.format = tagFormatOrExit(cmd)
+		opts.versions, _ = cmd.Flags().GetStringArray("version")
+		opts.constraint, _ = cmd.Flags().GetString("constraint")
+		opts.all, _ = cmd.Flags().GetBool("all")
+		if len(opts.tags) == 0 && len(opts.versions) == 0 && opts.constraint == "" && !opts.all {
+			exitWithUsageError(errors.New("no tags selected: specify tag names, --version, --constraint or --all"))
+		}
+
+		repository := openRepositoryOrExit(repoPath)
+
+		tags, err := selectTagsForDeletion(repository, opts)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		result := map[string]interface{}{
+			"tags":     tags,
+			"deleted":  false,
+			"repoRoot": repositoryRoot(repository),
+		}
+		if remote {
+			result["upstream"] = upstream
+		}
+		if dryRun {
+			result["dryRun"] = true
+		} else if len(tags) > 0 {
+			if !yes {
+				exitWithUsageError(fmt.Errorf("refusing to delete %d tag(s) without --yes; use --dry-run to preview", len(tags)))
+			}
+			// Delete remote tags first, so that a failed push can be retried with the local tags intact.
+			if remote {
+				if err := deleteRemoteTags(repository, upstream, tags, authOptionsFromFlags(cmd)); err != nil {
+					exitWithError(err)
+				}
+				result["remoteDeleted"] = true
+			}
+			for _, tag := range tags {
+				if err := repository.DeleteTag(tag.Tag); err != nil {
+					outputErrorAndExit(fmt.Sprintf("failed to delete tag %s: %v", tag.Tag, err))
+				}
+			}
+			result["deleted"] = true
+		}
+
+		writeOutput(result)
+	},
+}
+
+func init() {
+	deleteTagCmd.Flags().String("repo", ".", "Path to the Git repository or any directory inside it")
+	deleteTagCmd.Flags().String("prefix", "", "Select tags of this prefix with --version, --constraint or --all")
+	deleteTagCmd.Flags().String("tag-format", igit.DefaultTagFormat.String(), "Tag name template with {prefix} and {version} placeholders, or a preset: default, no-v")
+	deleteTagCmd.Flags().StringArray("version", nil, "Select the tag of this version for --prefix (repeatable)")
+	deleteTagCmd.Flags().String("constraint", "", "Select the tags for --prefix whose version satisfies the constraint, e.g. '>=1.2.0, <1.3.0'")
+	deleteTagCmd.Flags().Bool("all", false, "Select every version tag for --prefix")
+	deleteTagCmd.Flags().Bool("remote", false, "Also delete the tags on the upstream remote")
+	deleteTagCmd.Flags().String("upstream", "origin", "The remote to delete the tags from with --remote (default is 'origin')")
+	deleteTagCmd.Flags().Bool("yes", false, "Confirm the deletion of the selected tags")
+	deleteTagCmd.Flags().Bool("dry-run", false, "Print the selected tags without deleting them")
+	addAuthFlags(deleteTagCmd)
+
+	rootCmd.AddCommand(deleteTagCmd)
+}
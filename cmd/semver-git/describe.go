@@ -0,0 +1,50 @@
+package main
+
+import "github.com/spf13/cobra"
+
+// describe command: prints the version of a commit without modifying the repository. If the commit
+// already has a version tag for the prefix, that tag is returned; otherwise the version create-tag
+// would assign to it.
+var describeCmd = &cobra.Command{
+	Use:   "describe",
+	Short: "Print the version of a commit: its version tag, or the version create-tag would assign",
+	Run: func(cmd *cobra.Command, args []string) {
+		repoPath, _ := cmd.Flags().GetString("repo")
+
+		opts, err := createTagOptionsFromFlags(cmd)
+		if err != nil {
+			exitWithUsageError(err)
+		}
+		opts.ifUntagged = true
+		opts.taggedPolicy = taggedPolicyReuse
+
+		repository := openRepositoryOrExit(repoPath)
+
+		plan, err := computeTagPlan(repository, opts)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		result := map[string]interface{}{
+			"tag":      plan.Tag,
+			"version":  plan.Version,
+			"commit":   plan.Commit,
+			"tagged":   plan.Skipped,
+			"repoRoot": plan.RepoRoot,
+		}
+		if plan.Branch != "" {
+			result["branch"] = plan.Branch
+		}
+		if plan.PreviousTag != "" {
+			result["previousTag"] = plan.PreviousTag
+			result["previousVersion"] = plan.PreviousVersion
+		}
+		writeOutput(result)
+	},
+}
+
+func init() {
+	addVersionFlags(describeCmd)
+
+	rootCmd.AddCommand(describeCmd)
+}
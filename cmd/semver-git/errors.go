@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+
+	igit "github.com/coreeng/semver-utils/pkg/git"
+	"github.com/go-git/go-git/v5"
+)
+
+// errorCode classifies an error of semver-git: code is reported in the "code" field of the error
+// output and exitCode is the exit status. Both are stable, so scripts can rely on them.
+type errorCode struct {
+	code     string
+	exitCode int
+}
+
+var (
+	codeError              = errorCode{code: "error", exitCode: 1}
+	codeUsage              = errorCode{code: "usage", exitCode: 2}
+	codeRepositoryNotFound = errorCode{code: "repository-not-found", exitCode: 3}
+	codeNoVersionTag       = errorCode{code: "no-version-tag", exitCode: 4}
+	codeTagExists          = errorCode{code: "tag-exists", exitCode: 5}
+	codePushRejected       = errorCode{code: "push-rejected", exitCode: 6}
+	codeShallowRepository  = errorCode{code: "shallow-repository", exitCode: 7}
+	codeUnverifiedTag      = errorCode{code: "unverified-tag", exitCode: 8}
+	codePlanOutdated       = errorCode{code: "plan-outdated", exitCode: 9}
+)
+
+// Errors of semver-git itself that have a code, wrapped with igit.WrapSentinel.
+var (
+	// errUsage is an invalid flag or argument, such as a --commit that does not resolve.
+	errUsage = errors.New("invalid usage")
+	// errPlanOutdated is returned by apply if tags changed since the plan was made.
+	errPlanOutdated = errors.New("plan is outdated")
+)
+
+// errorCodes maps the errors of pkg/git and go-git to their code. The first entry found in the chain
+// of an error decides.
+var errorCodes = []struct {
+	err  error
+	code errorCode
+}{
+	{errUsage, codeUsage},
+	{errPlanOutdated, codePlanOutdated},
+	{igit.ErrShallowRepository, codeShallowRepository},
+	{igit.ErrTagNotSigned, codeUnverifiedTag},
+	{igit.ErrTagSignatureInvalid, codeUnverifiedTag},
+	{igit.ErrNoVersionTag, codeNoVersionTag},
+	{igit.ErrTagExists, codeTagExists},
+	{igit.ErrPushRejected, codePushRejected},
+	{git.ErrRepositoryNotExists, codeRepositoryNotFound},
+}
+
+// errorCodeOf returns the code of err, or codeError if it has none.
+func errorCodeOf(err error) errorCode {
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	return codeError
+}
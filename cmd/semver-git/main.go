@@ -1,26 +1,156 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/coreeng/semver-utils/internal/build"
 
 	igit "github.com/coreeng/semver-utils/pkg/git"
 	"github.com/coreeng/semver-utils/pkg/semver"
 	"github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/spf13/cobra"
 )
 
-// outputErrorAndExit prints an error message as JSON and exits the program.
+// outputErrorAndExit prints an error message in the --output format, with any secrets redacted, and
+// exits the program.
 func outputErrorAndExit(errMsg string) {
-	if err := json.NewEncoder(os.Stdout).Encode(map[string]string{"error": errMsg}); err != nil {
+	outputErrorDetailsAndExit(errMsg, codeError, nil)
+}
+
+// outputErrorDetailsAndExit prints an error message, its code and additional fields in the --output
+// format, with any secrets redacted, and exits the program with the exit status of the code.
+func outputErrorDetailsAndExit(errMsg string, code errorCode, details map[string]interface{}) {
+	result := map[string]interface{}{"error": errMsg, "code": code.code}
+	for key, value := range details {
+		result[key] = value
+	}
+	// Secrets are redacted from the values before they are encoded, which may escape them.
+	redacted, err := normalizeOutput(result)
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
+		os.Exit(1)
+	}
+	redacted = redactValues(redacted)
+	rendered, err := renderOutput(outputFormat, redacted)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error rendering output: %v\n", err)
+		os.Exit(1)
+	}
+	if outputFormat == outputGitHub {
+		// The error is still printed if the step outputs cannot be written.
+		_ = writeGitHubOutputs(redacted, os.Getenv)
+	}
+	fmt.Print(rendered)
+	os.Exit(code.exitCode)
+}
+
+// errorDetails is implemented by errors with structured details for the JSON error output.
+type errorDetails interface {
+	details() map[string]interface{}
+}
+
+// exitWithError prints an error with its code, including its details if it implements errorDetails,
+// and exits the program with the exit status of the code.
+func exitWithError(err error) {
+	var details map[string]interface{}
+	var detailed errorDetails
+	if errors.As(err, &detailed) {
+		details = detailed.details()
+	}
+	outputErrorDetailsAndExit(err.Error(), errorCodeOf(err), details)
+}
+
+// tagFormatOrExit parses the --tag-format flag of a command.
+func tagFormatOrExit(cmd *cobra.Command) igit.TagFormat {
+	formatStr, _ := cmd.Flags().GetString("tag-format")
+	format, err := igit.ParseTagFormat(formatStr)
+	if err != nil {
+		exitWithUsageError(err)
+	}
+	return format
+}
+
+// exitWithUsageError prints an error in the flags or arguments of a command and exits the program
+// with the exit status of codeUsage.
+func exitWithUsageError(err error) {
+	outputErrorDetailsAndExit(err.Error(), codeUsage, nil)
+}
+
+// openRepositoryOrExit opens the repository at or above repoPath, see igit.OpenRepository.
+func openRepositoryOrExit(repoPath string) *git.Repository {
+	repository, err := igit.OpenRepository(repoPath)
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to open repository: %w", err))
+	}
+	return repository
+}
+
+// repositoryRoot returns the root directory of the repository, reported as repoRoot.
+func repositoryRoot(repository *git.Repository) string {
+	root, err := igit.RepositoryRoot(repository)
+	if err != nil {
+		outputErrorAndExit(err.Error())
 	}
-	os.Exit(1)
+	return root
+}
+
+// addVersionTagDetails adds the details of a version tag found for commit to the fetch-tag result: the
+// tag object and its annotation, the number of commits since the tag, and the version components.
+func addVersionTagDetails(result map[string]interface{}, b igit.Backend, tagName string, version semver.SemVer, tagCommit, commit *object.Commit) error {
+	info, err := igit.FetchTagInfo(b, tagName)
+	if err != nil {
+		return err
+	}
+	result["tagHash"] = info.Hash.String()
+	result["annotated"] = info.Annotated()
+	if info.Annotated() {
+		result["taggerName"] = info.Object.Tagger.Name
+		result["taggerEmail"] = info.Object.Tagger.Email
+		result["taggerDate"] = info.Object.Tagger.When.Format(time.RFC3339)
+		result["message"] = strings.TrimRight(info.Object.Message, "\n")
+	}
+
+	distance, err := igit.CommitsSince(tagCommit, commit)
+	if err != nil {
+		return err
+	}
+	result["distance"] = distance
+
+	addVersionComponents(result, version)
+	return nil
+}
+
+// addVersionComponents adds the components of version to a fetch-tag result.
+func addVersionComponents(result map[string]interface{}, version semver.SemVer) {
+	result["major"] = version.Major
+	result["minor"] = version.Minor
+	result["patch"] = version.Patch
+	result["preRelease"] = string(version.PreRelease)
+	result["buildMetadata"] = string(version.BuildMetadata)
+}
+
+// fetchRemoteTags fetches the tags of the upstream remote, authenticating with auth. Local tags that
+// differ from the remote are only replaced if overwrite is true.
+func fetchRemoteTags(repository *git.Repository, upstream string, auth igit.AuthOptions, overwrite bool) (igit.FetchTagsResult, error) {
+	remoteAuth, err := igit.RemoteAuth(repository, upstream, auth)
+	if err != nil {
+		return igit.FetchTagsResult{}, fmt.Errorf("failed to fetch tags from remote %s: %v", upstream, err)
+	}
+	return igit.FetchTags(repository, upstream, remoteAuth, overwrite)
+}
+
+// listRemoteTags lists the tags fetchRemoteTags would fetch and update, without changing any ref.
+func listRemoteTags(repository *git.Repository, upstream string, auth igit.AuthOptions, overwrite bool) (igit.FetchTagsResult, error) {
+	remoteAuth, err := igit.RemoteAuth(repository, upstream, auth)
+	if err != nil {
+		return igit.FetchTagsResult{}, fmt.Errorf("failed to list tags of remote %s: %v", upstream, err)
+	}
+	return igit.ListRemoteTags(repository, upstream, remoteAuth, overwrite)
 }
 
 var rootCmd = &cobra.Command{
@@ -37,144 +167,104 @@ var fetchTagCmd = &cobra.Command{
 		commitRef, _ := cmd.Flags().GetString("commit")
 		prefix, _ := cmd.Flags().GetString("prefix")
 		exact, _ := cmd.Flags().GetBool("exact")
-
-		repository, err := git.PlainOpen(repoPath)
-		if err != nil {
-			outputErrorAndExit(fmt.Sprintf("failed to open repository: %v", err))
+		tagIndex, _ := cmd.Flags().GetBool("tag-index")
+		backendName, _ := cmd.Flags().GetString("backend")
+		fetch, _ := cmd.Flags().GetBool("fetch")
+		overwriteTags, _ := cmd.Flags().GetBool("overwrite-tags")
+		upstream, _ := cmd.Flags().GetString("upstream")
+		defaultVersionStr, _ := cmd.Flags().GetString("default-version")
+		format := tagFormatOrExit(cmd)
+		var defaultVersion *semver.SemVer
+		if defaultVersionStr != "" {
+			v, err := semver.Parse(defaultVersionStr)
+			if err != nil {
+				exitWithUsageError(fmt.Errorf("invalid default-version '%s': %v", defaultVersionStr, err))
+			}
+			defaultVersion = &v
 		}
-
-		commit, err := igit.FetchCommitObject(repository, commitRef)
+		verify, err := verificationFromFlags(cmd)
 		if err != nil {
-			outputErrorAndExit(fmt.Sprintf("failed to fetch commit object: %v", err))
+			exitWithUsageError(err)
 		}
-
-		tagName, version, tagCommit, err := igit.FetchVersionTag(repository, commit, prefix, exact)
+		shallow, err := shallowOptionsFromFlags(cmd)
 		if err != nil {
-			outputErrorAndExit(fmt.Sprintf("failed to fetch version tag: %v", err))
-		}
-		if tagName == "" {
-			outputErrorAndExit("No matching version tag found.")
+			exitWithUsageError(err)
 		}
 
-		result := map[string]string{
-			"tag":     tagName,
-			"version": version.String(),
-			"commit":  tagCommit.Hash.String(),
+		repository := openRepositoryOrExit(repoPath)
+
+		var fetched igit.FetchTagsResult
+		if fetch {
+			fetched, err = fetchRemoteTags(repository, upstream, authOptionsFromFlags(cmd), overwriteTags)
+			if err != nil {
+				exitWithError(err)
+			}
 		}
-		if err := json.NewEncoder(os.Stdout).Encode(result); err != nil {
-			outputErrorAndExit(fmt.Sprintf("Error encoding JSON: %v", err))
+		repository, err = deepenIfShallow(repository, commitRef, format, prefix, upstream, authOptionsFromFlags(cmd), shallow)
+		if err != nil {
+			exitWithError(err)
+		}
+		if err := updateTagIndex(repository, tagIndex); err != nil {
+			exitWithError(err)
 		}
-	},
-}
-
-// create-tag command: calls FetchVersionTag first. If a previous tag is found,
-// it increments the desired version field and then calls CreateVersionTag.
-// If no previous tag is found and --create-initial-version=true, it uses the provided --initial-version.
-var createTagCmd = &cobra.Command{
-	Use:   "create-tag",
-	Short: "Create an incremented semantic version tag on the specified commit",
-	Run: func(cmd *cobra.Command, args []string) {
-		repoPath, _ := cmd.Flags().GetString("repo")
-		commitRef, _ := cmd.Flags().GetString("commit")
-		prefix, _ := cmd.Flags().GetString("prefix")
-		incrementType, _ := cmd.Flags().GetString("increment-type")
-		annotated, _ := cmd.Flags().GetBool("annotated")
-		prerelease, _ := cmd.Flags().GetString("prerelease")
-		buildMetadata, _ := cmd.Flags().GetString("build-metadata")
-		pushTag, _ := cmd.Flags().GetBool("push")
-		upstream, _ := cmd.Flags().GetString("upstream")
-		createInitialVersion, _ := cmd.Flags().GetBool("create-initial-version")
-		initialVersionStr, _ := cmd.Flags().GetString("initial-version")
 
-		repository, err := git.PlainOpen(repoPath)
+		b, err := openBackend(repository, backendName)
 		if err != nil {
-			outputErrorAndExit(fmt.Sprintf("failed to open repository: %v", err))
+			exitWithError(err)
 		}
+		defer func() { _ = b.Close() }()
 
-		commit, err := igit.FetchCommitObject(repository, commitRef)
+		commit, err := fetchCommitObject(b, commitRef)
 		if err != nil {
-			outputErrorAndExit(fmt.Sprintf("failed to fetch commit object: %v", err))
+			exitWithUsageError(fmt.Errorf("failed to fetch commit object: %v", err))
 		}
 
-		// Try to fetch a previous version tag
-		prevTag, currentVersion, _, err := igit.FetchVersionTag(repository, commit, prefix, false)
+		tagName, version, tagCommit, signer, err := fetchVersionTag(b, commit, format, prefix, exact, false, verify)
 		if err != nil {
-			// We ignore the error here as it's not critical for version bumping
-			prevTag = ""
-			currentVersion = semver.SemVer{}
+			exitWithError(fmt.Errorf("failed to fetch version tag: %w", err))
 		}
-
-		var newVersion semver.SemVer
-		if prevTag != "" {
-			switch strings.ToLower(incrementType) {
-			case "major":
-				newVersion = currentVersion.BumpMajor()
-			case "minor":
-				newVersion = currentVersion.BumpMinor()
-			case "patch":
-				newVersion = currentVersion.BumpPatch()
-			default:
-				outputErrorAndExit("invalid increment type: must be 'major', 'minor', or 'patch'")
+		if tagName == "" {
+			if err := shallowError(repository, prefix); err != nil {
+				exitWithError(err)
 			}
-		} else {
-			// No previous tag found; create an initial version if allowed.
-			if createInitialVersion {
-				if initialVersionStr == "" {
-					outputErrorAndExit("initial-version must be specified when create-initial-version is true")
-				}
-				newVersion, err = semver.Parse(initialVersionStr)
-				if err != nil {
-					outputErrorAndExit(fmt.Sprintf("failed to parse initial version: %v", err))
-				}
-			} else {
-				outputErrorAndExit("No previous version tag found and create-initial-version is false.")
+			if defaultVersion == nil {
+				exitWithError(igit.WrapSentinel(igit.ErrNoVersionTag, "No matching version tag found."))
 			}
 		}
 
-		// Apply prerelease and build metadata if provided.
-		if prerelease != "" {
-			newVersion, err = newVersion.SetPreRelease(semver.PreRelease(prerelease))
-			if err != nil {
-				outputErrorAndExit(fmt.Sprintf("failed to set prerelease: %v", err))
-			}
-		}
-		if buildMetadata != "" {
-			newVersion, err = newVersion.SetBuildMetadata(semver.BuildMetadata(buildMetadata))
-			if err != nil {
-				outputErrorAndExit(fmt.Sprintf("failed to set build metadata: %v", err))
+		var result map[string]interface{}
+		if tagName == "" {
+			// No tag exists, so only the version is reported.
+			result = map[string]interface{}{
+				"tag":      "",
+				"version":  defaultVersion.String(),
+				"commit":   "",
+				"default":  true,
+				"repoRoot": repositoryRoot(repository),
 			}
-		}
-
-		// Create the new version tag.
-		newTag, err := igit.CreateVersionTag(repository, commit, newVersion, prefix, annotated)
-		if err != nil {
-			outputErrorAndExit(fmt.Sprintf("failed to create new tag: %v", err))
-		}
-
-		response := map[string]interface{}{
-			"tag":     newTag,
-			"version": newVersion.String(),
-			"commit":  commit.Hash.String(),
-		}
-
-		// Push the tag to remote if requested.
-		if pushTag {
-			pushOpts := &git.PushOptions{
-				RemoteName: upstream,
-				RefSpecs: []config.RefSpec{
-					config.RefSpec("refs/tags/" + newTag + ":refs/tags/" + newTag),
-				},
+			addVersionComponents(result, *defaultVersion)
+		} else {
+			result = map[string]interface{}{
+				"tag":      tagName,
+				"version":  version.String(),
+				"commit":   tagCommit.Hash.String(),
+				"default":  false,
+				"repoRoot": repositoryRoot(repository),
 			}
-			if err := repository.Push(pushOpts); err != nil {
-				outputErrorAndExit(fmt.Sprintf("failed to push tag %s to remote %s: %v", newTag, upstream, err))
+			if err := addVersionTagDetails(result, b, tagName, version, tagCommit, commit); err != nil {
+				outputErrorAndExit(fmt.Sprintf("failed to read version tag: %v", err))
 			}
-			response["pushed"] = true
-			response["upstream"] = upstream
 		}
-
-		if err := json.NewEncoder(os.Stdout).Encode(response); err != nil {
-			outputErrorAndExit(fmt.Sprintf("Error encoding JSON: %v", err))
+		if signer != nil {
+			result["signer"] = igit.SignerIdentity(signer)
+			result["signerKey"] = igit.SignerFingerprint(signer)
 		}
+		if fetch {
+			result["fetchedTags"] = fetched.New
+			result["updatedTags"] = fetched.Updated
+			result["conflictingTags"] = fetched.Conflicting
+		}
+		writeOutput(result)
 	},
 }
 
@@ -183,39 +273,46 @@ var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the semver-utils version information",
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("semver-git version: %s (commit: %s, built at: %s)\n",
-			build.BuildVersion, build.BuildCommit, build.BuildDate)
+		writeOutput(versionInfo{Version: build.BuildVersion, Commit: build.BuildCommit, BuildDate: build.BuildDate})
 	},
 }
 
+// versionInfo is the output of the version command.
+type versionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+}
+
+func (v versionInfo) outputText() string {
+	return fmt.Sprintf("semver-git version: %s (commit: %s, built at: %s)", v.Version, v.Commit, v.BuildDate)
+}
+
 func init() {
 	// Flags for fetch-tag command.
-	fetchTagCmd.Flags().String("repo", ".", "Path to the Git repository")
+	fetchTagCmd.Flags().String("repo", ".", "Path to the Git repository or any directory inside it")
 	fetchTagCmd.Flags().String("commit", "HEAD", "Git reference (commit hash, branch, tag, etc.)")
-	fetchTagCmd.Flags().String("prefix", "", "If set, the tag fetched will be formatted as <prefix>/v<semver>")
+	fetchTagCmd.Flags().String("prefix", "", "If set, the tag fetched will be formatted as <prefix>/v<semver> (see --tag-format)")
+	fetchTagCmd.Flags().String("tag-format", igit.DefaultTagFormat.String(), "Tag name template with {prefix} and {version} placeholders, or a preset: default, no-v")
 	fetchTagCmd.Flags().Bool("exact", false, "Match only if tag commit exactly equals the provided commit")
-
-	// Flags for create-tag command.
-	createTagCmd.Flags().String("repo", ".", "Path to the Git repository")
-	createTagCmd.Flags().String("commit", "HEAD", "Git reference (commit hash, branch, tag, etc.)")
-	createTagCmd.Flags().String("prefix", "", "If set, the tag created (and the previous version searched for) will be formatted as <prefix>/v<semver>")
-	createTagCmd.Flags().String("increment-type", "patch", "Version increment type: major, minor, or patch")
-	createTagCmd.Flags().Bool("annotated", false, "Create an annotated tag")
-	createTagCmd.Flags().String("prerelease", "", "Set the prerelease identifier for the new version (optional)")
-	createTagCmd.Flags().String("build-metadata", "", "Set the build metadata for the new version (optional)")
-	createTagCmd.Flags().Bool("push", false, "Push the new tag to a remote repository after creation? (default is false)")
-	createTagCmd.Flags().String("upstream", "origin", "The remote to push the new tag to (default is 'origin')")
-	createTagCmd.Flags().Bool("create-initial-version", false, "If true, create an initial version if no previous version tag is found (default is false)")
-	createTagCmd.Flags().String("initial-version", "", "Specify the initial semantic version to use if no previous version tag is found (required if create-initial-version is true)")
+	fetchTagCmd.Flags().String("default-version", "", "Report this version instead of failing if no matching version tag is found")
+	fetchTagCmd.Flags().Bool("fetch", false, "Fetch the tags of the upstream remote before searching")
+	fetchTagCmd.Flags().Bool("overwrite-tags", false, "With --fetch, replace local tags that differ from the upstream remote instead of keeping them")
+	fetchTagCmd.Flags().String("upstream", "origin", "The remote to fetch tags from with --fetch, --deepen or --unshallow (default is 'origin')")
+	addShallowFlags(fetchTagCmd)
+	addTagIndexFlag(fetchTagCmd)
+	addBackendFlag(fetchTagCmd)
+	addAuthFlags(fetchTagCmd)
+	addVerifyFlags(fetchTagCmd)
 
 	// Add subcommands to the root command.
 	rootCmd.AddCommand(fetchTagCmd)
-	rootCmd.AddCommand(createTagCmd)
+	versionCmd.Flags().String("output", outputText, "Output format: "+strings.Join(outputFormats, ", "))
 	rootCmd.AddCommand(versionCmd)
 }
 
 func main() {
 	if err := rootCmd.Execute(); err != nil {
-		outputErrorAndExit(err.Error())
+		exitWithUsageError(err)
 	}
 }
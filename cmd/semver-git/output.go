@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats of the --output flag.
+const (
+	outputJSON   = "json"
+	outputText   = "text"
+	outputEnv    = "env"
+	outputShell  = "shell"
+	outputYAML   = "yaml"
+	outputGitHub = "github"
+)
+
+// Environment variables of the files GitHub Actions reads step outputs and the job summary from.
+const (
+	envGitHubOutput      = "GITHUB_OUTPUT"
+	envGitHubStepSummary = "GITHUB_STEP_SUMMARY"
+)
+
+var outputFormats = []string{outputJSON, outputText, outputEnv, outputShell, outputYAML, outputGitHub}
+
+// outputFormat is the --output format of the running command, and outputCommand its name.
+// Errors found before the flags are read are printed as JSON.
+var (
+	outputFormat  = outputJSON
+	outputCommand = ""
+)
+
+// textOutput is implemented by results with their own text format.
+type textOutput interface {
+	outputText() string
+}
+
+// setOutputFormat reads the --output flag of the running command.
+func setOutputFormat(cmd *cobra.Command) error {
+	format, _ := cmd.Flags().GetString("output")
+	for _, f := range outputFormats {
+		if format == f {
+			outputFormat = format
+			outputCommand = strings.Replace(cmd.CommandPath(), rootCmd.Name(), "semver-git", 1)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid output '%s': must be one of %s", format, strings.Join(outputFormats, ", "))
+}
+
+// writeOutput prints the result of a command in the --output format; the github format also writes
+// it to the step outputs and job summary. It exits with an error if that fails.
+func writeOutput(result interface{}) {
+	rendered, err := renderOutput(outputFormat, result)
+	if err == nil && outputFormat == outputGitHub {
+		err = writeGitHubOutputs(result, os.Getenv)
+	}
+	if err != nil {
+		outputErrorAndExit(fmt.Sprintf("failed to write output: %v", err))
+	}
+	fmt.Print(rendered)
+}
+
+// renderOutput formats result, a value that encodes to a JSON object, for stdout. The env, shell and
+// text formats flatten nested objects into one field per value, see flattenOutput; the github format
+// prints JSON.
+func renderOutput(format string, result interface{}) (string, error) {
+	if text, ok := result.(textOutput); ok && format == outputText {
+		return text.outputText() + "\n", nil
+	}
+	switch format {
+	case outputJSON, outputGitHub:
+		data, err := json.Marshal(result)
+		if err != nil {
+			return "", err
+		}
+		return string(data) + "\n", nil
+	case outputYAML:
+		value, err := normalizeOutput(result)
+		if err != nil {
+			return "", err
+		}
+		data, err := yaml.Marshal(yamlNumbers(value))
+		return string(data), err
+	}
+
+	fields, err := flattenOutput(result)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	for _, field := range fields {
+		switch format {
+		case outputText:
+			fmt.Fprintf(&out, "%s: %s\n", strings.Join(field.path, "."), field.value)
+		case outputEnv:
+			fmt.Fprintf(&out, "%s=%s\n", envName(field.path), quoteEnv(field.value))
+		case outputShell:
+			fmt.Fprintf(&out, "export %s=%s\n", envName(field.path), quoteShell(field.value))
+		default:
+			return "", fmt.Errorf("unknown output format '%s'", format)
+		}
+	}
+	return out.String(), nil
+}
+
+// outputField is a value of a result and the keys of the objects leading to it.
+type outputField struct {
+	path  []string
+	value string
+}
+
+// flattenOutput returns the values of result, ordered by key. The values of nested objects become
+// fields of their own, while arrays are kept as JSON; empty keys, such as the prefix of tags without
+// a prefix, are left out of the path.
+func flattenOutput(result interface{}) ([]outputField, error) {
+	value, err := normalizeOutput(result)
+	if err != nil {
+		return nil, err
+	}
+	var fields []outputField
+	var walk func(path []string, value interface{}) error
+	walk = func(path []string, value interface{}) error {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				next := path[:len(path):len(path)]
+				if key != "" {
+					next = append(next, key)
+				}
+				if err := walk(next, v[key]); err != nil {
+					return err
+				}
+			}
+		case string:
+			fields = append(fields, outputField{path: path, value: v})
+		case json.Number:
+			fields = append(fields, outputField{path: path, value: v.String()})
+		case bool:
+			fields = append(fields, outputField{path: path, value: fmt.Sprint(v)})
+		case nil:
+			fields = append(fields, outputField{path: path})
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			fields = append(fields, outputField{path: path, value: string(data)})
+		}
+		return nil
+	}
+	if err := walk(nil, value); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// normalizeOutput converts result to the maps, slices and scalars its JSON encoding decodes to, so
+// that structs and maps are rendered alike. Numbers are kept as json.Number.
+func normalizeOutput(result interface{}) (interface{}, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// yamlNumbers replaces the json.Number values in value by numbers, which YAML would quote as strings.
+func yamlNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = yamlNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = yamlNumbers(item)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+	}
+	return value
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// envName returns the environment variable name of a field: its keys in upper snake case, joined with
+// underscores, e.g. SERVICES_API_PREVIOUS_TAG for services/api.previousTag.
+func envName(path []string) string {
+	var words []string
+	for _, key := range path {
+		var word strings.Builder
+		runes := []rune(key)
+		for i, r := range runes {
+			// Split camelCase keys: repoRoot becomes REPO_ROOT.
+			if i > 0 && unicode.IsUpper(r) && unicode.IsLower(runes[i-1]) {
+				word.WriteRune('_')
+			}
+			word.WriteRune(unicode.ToUpper(r))
+		}
+		words = append(words, word.String())
+	}
+	name := strings.Trim(nonAlphanumeric.ReplaceAllString(strings.Join(words, "_"), "_"), "_")
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "_" + name
+	}
+	return name
+}
+
+// githubOutputName returns the GitHub Actions step output name of a field: its keys joined with
+// underscores, e.g. services_api_previousTag.
+func githubOutputName(path []string) string {
+	return strings.Trim(nonAlphanumeric.ReplaceAllString(strings.Join(path, "_"), "_"), "_")
+}
+
+var plainEnvValue = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,=-]*$`)
+
+// quoteEnv quotes a value for a dotenv file: values with other characters than letters, digits and
+// _./:@%+,=- are double-quoted, with backslashes, quotes, dollar signs, backticks and newlines escaped.
+func quoteEnv(value string) string {
+	if plainEnvValue.MatchString(value) {
+		return value
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "`", "\\`", "\n", `\n`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// quoteShell single-quotes a value for a POSIX shell.
+func quoteShell(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// writeGitHubOutputs appends the fields of result to the step outputs file of GitHub Actions and, if
+// the job summary file is set, a table of them to the summary.
+func writeGitHubOutputs(result interface{}, getenv func(string) string) error {
+	outputFile := getenv(envGitHubOutput)
+	if outputFile == "" {
+		return fmt.Errorf("the github output format requires %s to be set", envGitHubOutput)
+	}
+	fields, err := flattenOutput(result)
+	if err != nil {
+		return err
+	}
+
+	var outputs strings.Builder
+	for _, field := range fields {
+		name := githubOutputName(field.path)
+		if !strings.Contains(field.value, "\n") {
+			fmt.Fprintf(&outputs, "%s=%s\n", name, field.value)
+			continue
+		}
+		delimiter, err := githubDelimiter()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&outputs, "%s<<%s\n%s\n%s\n", name, delimiter, field.value, delimiter)
+	}
+	if err := appendFile(outputFile, outputs.String()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", envGitHubOutput, err)
+	}
+
+	summaryFile := getenv(envGitHubStepSummary)
+	if summaryFile == "" {
+		return nil
+	}
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "### %s\n\n| Output | Value |\n|--------|-------|\n", outputCommand)
+	cell := strings.NewReplacer("|", `\|`, "\n", "<br>", "`", "'")
+	for _, field := range fields {
+		value := ""
+		if field.value != "" {
+			value = "`" + cell.Replace(field.value) + "`"
+		}
+		fmt.Fprintf(&summary, "| %s | %s |\n", githubOutputName(field.path), value)
+	}
+	summary.WriteString("\n")
+	if err := appendFile(summaryFile, summary.String()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", envGitHubStepSummary, err)
+	}
+	return nil
+}
+
+// githubDelimiter returns a random delimiter for a multi-line step output, which cannot occur in the value.
+func githubDelimiter() (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return "ghadelimiter_" + hex.EncodeToString(random), nil
+}
+
+// appendFile appends data to the file at path, creating it if needed.
+func appendFile(path, data string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func init() {
+	rootCmd.PersistentFlags().String("output", outputJSON, "Output format: "+strings.Join(outputFormats, ", ")+" (github appends to $"+envGitHubOutput+" and $"+envGitHubStepSummary+")")
+}
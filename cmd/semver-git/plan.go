@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	igit "github.com/coreeng/semver-utils/pkg/git"
+	"github.com/spf13/cobra"
+)
+
+// plan command: computes the tag create-tag would create and writes it to a plan file,
+// which can be executed later with the apply command.
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Compute the tag create-tag would create and write it to a plan file",
+	Run: func(cmd *cobra.Command, args []string) {
+		repoPath, _ := cmd.Flags().GetString("repo")
+		planFile, _ := cmd.Flags().GetString("plan-file")
+
+		opts, err := createTagOptionsFromFlags(cmd)
+		if err != nil {
+			exitWithUsageError(err)
+		}
+
+		repository := openRepositoryOrExit(repoPath)
+		repository, err = deepenIfShallow(repository, opts.commitRef, opts.format, opts.prefix, opts.upstream, opts.auth, opts.shallow)
+		if err != nil {
+			exitWithError(err)
+		}
+		if err := updateTagIndex(repository, opts.tagIndex); err != nil {
+			exitWithError(err)
+		}
+
+		plan, err := computeTagPlan(repository, opts)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			outputErrorAndExit(fmt.Sprintf("Error encoding JSON: %v", err))
+		}
+		if err := os.WriteFile(planFile, append(data, '\n'), 0o644); err != nil {
+			outputErrorAndExit(fmt.Sprintf("failed to write plan file: %v", err))
+		}
+
+		writeOutput(plan)
+	},
+}
+
+// apply command: executes a plan file written by the plan command. It refuses to run if any tag
+// was created, deleted or moved since the plan was made.
+var applyCmd = &cobra.Command{
+	Use:   "apply <plan-file>",
+	Short: "Create the tag described by a plan file",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		repoPath, _ := cmd.Flags().GetString("repo")
+
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			exitWithUsageError(fmt.Errorf("failed to read plan file: %v", err))
+		}
+		var plan tagPlan
+		if err := json.Unmarshal(data, &plan); err != nil {
+			exitWithUsageError(fmt.Errorf("failed to parse plan file: %v", err))
+		}
+
+		repository := openRepositoryOrExit(repoPath)
+		opts := executeOptionsFromFlags(cmd)
+
+		b, err := openBackend(repository, opts.backend)
+		if err != nil {
+			exitWithError(err)
+		}
+		err = checkPlanState(b, &plan)
+		_ = b.Close()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		response, err := executeTagPlan(repository, &plan, opts)
+		if err != nil {
+			exitWithError(err)
+		}
+		writeOutput(response)
+	},
+}
+
+// checkPlanState verifies with b that the repository tags are unchanged since the plan was made.
+func checkPlanState(b igit.Backend, plan *tagPlan) error {
+	if plan.RepoState == "" || plan.Commit == "" {
+		return igit.WrapSentinel(errUsage, "invalid plan file: missing repository state or commit")
+	}
+	repoState, err := igit.TagsFingerprint(b)
+	if err != nil {
+		return fmt.Errorf("failed to read repository state: %v", err)
+	}
+	if repoState != plan.RepoState {
+		return igit.WrapSentinel(errPlanOutdated, "repository state changed since the plan was made: tags were created, deleted or moved")
+	}
+	return nil
+}
+
+func init() {
+	addCreateTagFlags(planCmd)
+	planCmd.Flags().String("plan-file", "semver-git-plan.json", "Path of the plan file to write")
+
+	applyCmd.Flags().String("repo", ".", "Path to the Git repository or any directory inside it")
+	addAuthFlags(applyCmd)
+	addExecuteFlags(applyCmd)
+	addBackendFlag(applyCmd)
+
+	rootCmd.AddCommand(planCmd)
+	rootCmd.AddCommand(applyCmd)
+}
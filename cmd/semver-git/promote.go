@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	igit "github.com/coreeng/semver-utils/pkg/git"
+	"github.com/coreeng/semver-utils/pkg/semver"
+	"github.com/go-git/go-git/v5"
+	"github.com/spf13/cobra"
+)
+
+// promoteOptions holds the flags of the promote command.
+type promoteOptions struct {
+	sourceTag         string
+	prefix            string
+	format            igit.TagFormat
+	to                string
+	prereleaseCounter bool
+	annotated         bool
+	sign              bool
+	copyMessage       bool
+	push              bool
+	upstream          string
+	backend           string
+}
+
+// latestPreReleaseTag returns the prerelease tag with the highest version for prefix.
+func latestPreReleaseTag(b igit.Backend, format igit.TagFormat, prefix string) (string, error) {
+	versions, err := igit.ListVersionTags(b, format, prefix)
+	if err != nil {
+		return "", fmt.Errorf("failed to list version tags: %v", err)
+	}
+	var latestTag string
+	var latestVersion semver.SemVer
+	for tag, version := range versions {
+		if version.PreRelease == "" {
+			continue
+		}
+		if latestTag == "" || version.Compare(latestVersion) > 0 || (version.Compare(latestVersion) == 0 && tag > latestTag) {
+			latestTag = tag
+			latestVersion = version
+		}
+	}
+	if latestTag == "" {
+		return "", igit.WrapSentinel(igit.ErrNoVersionTag, "no prerelease version tag found for prefix '%s'", prefix)
+	}
+	return latestTag, nil
+}
+
+// computePromotePlan determines the tag a prerelease tag is promoted to, on the same commit.
+func computePromotePlan(repository *git.Repository, opts promoteOptions) (*tagPlan, error) {
+	b, err := openBackend(repository, opts.backend)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = b.Close() }()
+
+	sourceTag := opts.sourceTag
+	if sourceTag == "" {
+		sourceTag, err = latestPreReleaseTag(b, opts.format, opts.prefix)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	prefix, sourceVersion, ok := opts.format.Parse(sourceTag)
+	if !ok {
+		return nil, fmt.Errorf("tag '%s' is not a version tag in the format %s", sourceTag, opts.format)
+	}
+	if sourceVersion.PreRelease == "" {
+		return nil, fmt.Errorf("tag '%s' is not a prerelease", sourceTag)
+	}
+	commit, err := fetchCommitObject(b, "refs/tags/"+sourceTag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch commit object: %v", err)
+	}
+
+	repoState, err := igit.TagsFingerprint(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository state: %v", err)
+	}
+
+	plan := &tagPlan{
+		Commit:          commit.Hash.String(),
+		Prefix:          prefix,
+		TagFormat:       opts.format.String(),
+		PreviousTag:     sourceTag,
+		PreviousVersion: sourceVersion.String(),
+		Annotated:       opts.annotated || opts.copyMessage || opts.sign,
+		Signed:          opts.sign,
+		Push:            opts.push,
+		Upstream:        opts.upstream,
+		RepoState:       repoState,
+		RepoRoot:        repositoryRoot(repository),
+	}
+
+	newVersion := sourceVersion.Release()
+	if opts.to != "" {
+		prerelease := opts.to
+		if opts.prereleaseCounter {
+			counter, err := igit.NextPreReleaseCounter(b, opts.format, prefix, newVersion, semver.PreRelease(prerelease))
+			if err != nil {
+				return nil, fmt.Errorf("failed to determine prerelease counter: %v", err)
+			}
+			prerelease = fmt.Sprintf("%s.%d", prerelease, counter)
+		}
+		newVersion, err = newVersion.SetPreRelease(semver.PreRelease(prerelease))
+		if err != nil {
+			return nil, fmt.Errorf("failed to set prerelease: %v", err)
+		}
+		if newVersion.Compare(sourceVersion) <= 0 {
+			return nil, fmt.Errorf("cannot promote %s to %s: the new version must be higher", sourceTag, newVersion)
+		}
+	}
+
+	if opts.copyMessage {
+		info, err := igit.FetchTagInfo(b, sourceTag)
+		if err != nil {
+			return nil, err
+		}
+		if info.Annotated() {
+			plan.Message = info.Object.Message
+		}
+	}
+
+	if err := igit.CheckVersionTagAvailable(b, opts.format, prefix, newVersion); err != nil {
+		return nil, fmt.Errorf("failed to create new tag: %w", err)
+	}
+	plan.Tag, err = opts.format.Format(prefix, newVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new tag: %w", err)
+	}
+	plan.Version = newVersion.String()
+
+	return plan, nil
+}
+
+// promote command: turns a prerelease tag (or the latest one for --prefix) into a release, or a
+// prerelease of another channel, on the same commit.
+var promoteCmd = &cobra.Command{
+	Use:   "promote [<tag>]",
+	Short: "Create a release (or another prerelease channel) tag on the commit of a prerelease tag",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		repoPath, _ := cmd.Flags().GetString("repo")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		var opts promoteOptions
+		if len(args) > 0 {
+			opts.sourceTag = args[0]
+		}
+		opts.prefix, _ = cmd.Flags().GetString("prefix")
+		opts.format = tagFormatOrExit(cmd)
+		opts.to, _ = cmd.Flags().GetString("to")
+		opts.prereleaseCounter, _ = cmd.Flags().GetBool("prerelease-counter")
+		opts.annotated, _ = cmd.Flags().GetBool("annotated")
+		opts.sign, _ = cmd.Flags().GetBool("sign")
+		opts.copyMessage, _ = cmd.Flags().GetBool("copy-message")
+		opts.push, _ = cmd.Flags().GetBool("push")
+		opts.upstream, _ = cmd.Flags().GetString("upstream")
+		opts.backend, _ = cmd.Flags().GetString("backend")
+		if opts.sourceTag != "" && cmd.Flags().Changed("prefix") {
+			exitWithUsageError(errors.New("--prefix cannot be combined with a tag argument"))
+		}
+
+		repository := openRepositoryOrExit(repoPath)
+
+		plan, err := computePromotePlan(repository, opts)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		var response interface{} = plan
+		if dryRun {
+			plan.DryRun = true
+		} else {
+			result, err := executeTagPlan(repository, plan, executeOptionsFromFlags(cmd))
+			if err != nil {
+				exitWithError(err)
+			}
+			result["promotedFrom"] = plan.PreviousTag
+			response = result
+		}
+
+		writeOutput(response)
+	},
+}
+
+func init() {
+	promoteCmd.Flags().String("repo", ".", "Path to the Git repository or any directory inside it")
+	promoteCmd.Flags().String("prefix", "", "Promote the latest prerelease tag for this prefix when no tag is given")
+	promoteCmd.Flags().String("tag-format", igit.DefaultTagFormat.String(), "Tag name template with {prefix} and {version} placeholders, or a preset: default, no-v")
+	promoteCmd.Flags().String("to", "", "Prerelease channel to promote to, e.g. rc (default: release, without prerelease)")
+	promoteCmd.Flags().Bool("prerelease-counter", false, "Append a counter to the --to channel one higher than the existing tags of the same version")
+	promoteCmd.Flags().Bool("annotated", false, "Create an annotated tag")
+	promoteCmd.Flags().Bool("sign", false, "Create an annotated tag signed with the OpenPGP key given by --signing-key")
+	promoteCmd.Flags().Bool("copy-message", false, "Create an annotated tag with the message of the promoted tag, if it is annotated")
+	promoteCmd.Flags().Bool("push", false, "Push the new tag to a remote repository after creation? (default is false)")
+	promoteCmd.Flags().String("upstream", "origin", "The remote to push the new tag to (default is 'origin')")
+	promoteCmd.Flags().Bool("dry-run", false, "Compute and print the tag that would be created without modifying the repository or remotes")
+	addAuthFlags(promoteCmd)
+	addExecuteFlags(promoteCmd)
+	addBackendFlag(promoteCmd)
+
+	rootCmd.AddCommand(promoteCmd)
+}
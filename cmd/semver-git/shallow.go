@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	igit "github.com/coreeng/semver-utils/pkg/git"
+	"github.com/go-git/go-git/v5"
+	"github.com/spf13/cobra"
+)
+
+// shallowOptions holds the flags that deepen shallow clones.
+type shallowOptions struct {
+	deepen    int
+	unshallow bool
+}
+
+// addShallowFlags registers the flags read by shallowOptionsFromFlags.
+func addShallowFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("deepen", 0, "In a shallow clone, fetch this many more commits (doubling each time) and their tags from --upstream until a version tag is reachable")
+	cmd.Flags().Bool("unshallow", false, "In a shallow clone, fetch the complete history and tags from --upstream if no version tag is reachable")
+}
+
+// shallowOptionsFromFlags reads the flags registered by addShallowFlags.
+func shallowOptionsFromFlags(cmd *cobra.Command) (shallowOptions, error) {
+	var opts shallowOptions
+	opts.deepen, _ = cmd.Flags().GetInt("deepen")
+	opts.unshallow, _ = cmd.Flags().GetBool("unshallow")
+	if opts.deepen < 0 {
+		return opts, fmt.Errorf("invalid deepen %d: must not be negative", opts.deepen)
+	}
+	return opts, nil
+}
+
+// deepenIfShallow fetches more history into a shallow clone from upstream, as configured by opts,
+// until a version tag for prefix is reachable from commitRef. It returns the repository to use from
+// then on, which is reopened if history was fetched.
+func deepenIfShallow(repository *git.Repository, commitRef string, format igit.TagFormat, prefix, upstream string, auth igit.AuthOptions, opts shallowOptions) (*git.Repository, error) {
+	if opts.deepen == 0 && !opts.unshallow {
+		return repository, nil
+	}
+	shallow, err := igit.IsShallow(repository)
+	if err != nil || !shallow {
+		return repository, err
+	}
+	remoteAuth, err := igit.RemoteAuth(repository, upstream, auth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to deepen history from remote %s: %v", upstream, err)
+	}
+	depth := opts.deepen
+	if opts.unshallow {
+		depth = 0
+	}
+	return igit.DeepenUntil(repository, upstream, depth, remoteAuth, func(repo *git.Repository) (bool, error) {
+		commit, err := igit.FetchCommitObject(repo, commitRef)
+		if err != nil {
+			return false, fmt.Errorf("failed to fetch commit object: %v", err)
+		}
+		tag, err := igit.FetchVersionTagContext(context.Background(), repo.Storer, igit.FetchOptions{
+			Revision: commit.Hash.String(), Prefix: prefix, Format: format,
+		})
+		if err != nil {
+			return false, nil
+		}
+		// The tag must be reachable, so that the commits since it can be counted.
+		reachable, err := tag.Commit.IsAncestor(commit)
+		return err == nil && reachable, nil
+	})
+}
+
+// shallowError returns the error reported when no version tag for prefix is found in a shallow
+// clone, or nil if the repository is not shallow.
+func shallowError(repository *git.Repository, prefix string) error {
+	if shallow, err := igit.IsShallow(repository); err != nil || !shallow {
+		return err
+	}
+	return fmt.Errorf("no version tag for prefix '%s' found in the fetched history: %w; fetch the complete history (e.g. fetch-depth: 0) or use --deepen or --unshallow", prefix, igit.ErrShallowRepository)
+}
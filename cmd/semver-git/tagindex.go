@@ -0,0 +1,21 @@
+package main
+
+import (
+	igit "github.com/coreeng/semver-utils/pkg/git"
+	"github.com/go-git/go-git/v5"
+	"github.com/spf13/cobra"
+)
+
+// addTagIndexFlag registers the --tag-index flag.
+func addTagIndexFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("tag-index", false, "Build or refresh the tag index in the git directory before looking up version tags, which speeds up lookups in repositories with many tags")
+}
+
+// updateTagIndex writes the tag index of the repository if enabled and it is out of date.
+func updateTagIndex(repository *git.Repository, enabled bool) error {
+	if !enabled {
+		return nil
+	}
+	_, err := igit.UpdateTagIndex(repository)
+	return err
+}
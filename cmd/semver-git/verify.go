@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	igit "github.com/coreeng/semver-utils/pkg/git"
+	"github.com/coreeng/semver-utils/pkg/semver"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/spf13/cobra"
+)
+
+// Policies for --verify-signatures when a version tag is not signed by a trusted key.
+const (
+	unverifiedPolicyIgnore = "ignore"
+	unverifiedPolicyFail   = "fail"
+)
+
+// addVerifyFlags registers the flags read by verificationFromFlags.
+func addVerifyFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("verify-signatures", false, "Only use version tags signed by one of the --trusted-keys")
+	cmd.Flags().String("trusted-keys", "", "Path of the armored or binary OpenPGP keyring that version tags must be signed with")
+	cmd.Flags().String("unverified-policy", unverifiedPolicyIgnore, "With --verify-signatures, what to do with version tags that are not signed by a trusted key: ignore (skip them) or fail")
+}
+
+// verificationFromFlags reads the flags registered by addVerifyFlags and loads the trusted keys. It
+// returns nil if signatures are not verified.
+func verificationFromFlags(cmd *cobra.Command) (*igit.SignatureVerification, error) {
+	verify, _ := cmd.Flags().GetBool("verify-signatures")
+	if !verify {
+		return nil, nil
+	}
+	trustedKeys, _ := cmd.Flags().GetString("trusted-keys")
+	policy, _ := cmd.Flags().GetString("unverified-policy")
+	if policy != unverifiedPolicyIgnore && policy != unverifiedPolicyFail {
+		return nil, fmt.Errorf("invalid unverified-policy '%s': must be '%s' or '%s'", policy, unverifiedPolicyIgnore, unverifiedPolicyFail)
+	}
+	if trustedKeys == "" {
+		return nil, fmt.Errorf("verify-signatures requires --trusted-keys")
+	}
+	keys, err := igit.LoadTrustedKeys(trustedKeys)
+	if err != nil {
+		return nil, err
+	}
+	return &igit.SignatureVerification{TrustedKeys: keys, FailOnUnverified: policy == unverifiedPolicyFail}, nil
+}
+
+// fetchVersionTag looks up a version tag of commit with b, only considering tags signed by a trusted
+// key if verify is not nil, and only release versions if releaseOnly is true. It also returns the key
+// that signed the tag. Like igit.FetchVersionTag, the tag name is empty if no tag matches.
+func fetchVersionTag(b igit.Backend, commit *object.Commit, format igit.TagFormat, prefix string, exact, releaseOnly bool, verify *igit.SignatureVerification) (string, semver.SemVer, *object.Commit, *openpgp.Entity, error) {
+	var filter func(string, semver.SemVer) bool
+	if releaseOnly {
+		filter = func(_ string, version semver.SemVer) bool { return version.PreRelease == "" }
+	}
+	tag, err := igit.FetchVersionTagContext(context.Background(), nil, igit.FetchOptions{
+		Revision:    commit.Hash.String(),
+		Prefix:      prefix,
+		Format:      format,
+		ExactCommit: exact,
+		Filter:      filter,
+		Verify:      verify,
+		Backend:     b,
+	})
+	if errors.Is(err, igit.ErrNoVersionTag) {
+		return "", semver.SemVer{}, nil, nil, nil
+	}
+	return tag.Name, tag.Version, tag.Commit, tag.Signer, err
+}
@@ -0,0 +1,244 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of the configuration file looked up in the repository root.
+const FileName = ".semver-git.yaml"
+
+// GitConfigSection is the git config section holding semver-git defaults.
+const GitConfigSection = "semver"
+
+// EnvPrefix is prepended to the upper-cased flag name to form the environment variable for a flag,
+// e.g. SEMVER_GIT_INITIAL_VERSION for --initial-version.
+const EnvPrefix = "SEMVER_GIT_"
+
+// Sources a setting can come from, in increasing order of precedence.
+const (
+	SourceDefault    = "default"
+	SourceConfigFile = "config-file"
+	SourceGitConfig  = "git-config"
+	SourceEnv        = "env"
+	SourceFlag       = "flag"
+)
+
+// PathsKey is the per-prefix setting listing the paths of the component, equivalent to
+// passing --path=<prefix>=<path> for every entry.
+const PathsKey = "paths"
+
+// Settings maps flag names to their configured values. Scalars are stored as a single value.
+type Settings map[string][]string
+
+// UnmarshalYAML accepts both scalar and list values for every key.
+func (s *Settings) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: expected a mapping of settings", node.Line)
+	}
+	*s = make(Settings, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch value.Kind {
+		case yaml.ScalarNode:
+			(*s)[key.Value] = []string{value.Value}
+		case yaml.SequenceNode:
+			values := make([]string, 0, len(value.Content))
+			for _, item := range value.Content {
+				if item.Kind != yaml.ScalarNode {
+					return fmt.Errorf("line %d: '%s' must be a list of scalars", item.Line, key.Value)
+				}
+				values = append(values, item.Value)
+			}
+			(*s)[key.Value] = values
+		default:
+			return fmt.Errorf("line %d: '%s' must be a scalar or a list of scalars", value.Line, key.Value)
+		}
+	}
+	return nil
+}
+
+// Config holds defaults that apply globally, to a single tag prefix, or to branches whose name
+// matches a pattern (using path.Match syntax, e.g. release/*).
+type Config struct {
+	Defaults Settings            `yaml:"defaults"`
+	Prefixes map[string]Settings `yaml:"prefixes"`
+	Branches map[string]Settings `yaml:"branches"`
+}
+
+// Value is a resolved setting along with where it came from.
+type Value struct {
+	Values []string
+	Source string
+}
+
+// Layered is a stack of configurations, where later layers take precedence over earlier ones.
+type Layered struct {
+	layers  []*Config
+	sources []string
+}
+
+// Load reads the configuration file in the repository root (if any) and the semver.* keys of the
+// repository's git config, with the git config taking precedence over the file.
+func Load(repo *git.Repository) (*Layered, error) {
+	l := &Layered{}
+
+	if wt, err := repo.Worktree(); err == nil {
+		fileCfg, err := LoadFile(filepath.Join(wt.Filesystem.Root(), FileName))
+		if err != nil {
+			return nil, err
+		}
+		if fileCfg != nil {
+			l.Add(fileCfg, SourceConfigFile)
+		}
+	}
+
+	rawCfg, err := repo.ConfigScoped(gitconfig.GlobalScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read git config: %w", err)
+	}
+	l.Add(FromGitConfig(rawCfg), SourceGitConfig)
+
+	return l, nil
+}
+
+// LoadFile reads a YAML configuration file. It returns nil without error if the file does not exist.
+func LoadFile(filePath string) (*Config, error) {
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+	return &cfg, nil
+}
+
+// FromGitConfig builds a Config from the semver section of a git config:
+//
+//	[semver]
+//		initial-version = 0.1.0
+//	[semver "prefix:services/api"]
+//		annotated = true
+//	[semver "branch:release/*"]
+//		push = true
+func FromGitConfig(cfg *gitconfig.Config) *Config {
+	result := &Config{
+		Defaults: Settings{},
+		Prefixes: map[string]Settings{},
+		Branches: map[string]Settings{},
+	}
+	if cfg == nil || cfg.Raw == nil || !cfg.Raw.HasSection(GitConfigSection) {
+		return result
+	}
+
+	section := cfg.Raw.Section(GitConfigSection)
+	for _, opt := range section.Options {
+		key := strings.ToLower(opt.Key)
+		result.Defaults[key] = append(result.Defaults[key], opt.Value)
+	}
+	for _, sub := range section.Subsections {
+		var target map[string]Settings
+		var name string
+		if prefix, ok := strings.CutPrefix(sub.Name, "prefix:"); ok {
+			target, name = result.Prefixes, prefix
+		} else if branch, ok := strings.CutPrefix(sub.Name, "branch:"); ok {
+			target, name = result.Branches, branch
+		} else {
+			continue
+		}
+		settings := Settings{}
+		for _, opt := range sub.Options {
+			key := strings.ToLower(opt.Key)
+			settings[key] = append(settings[key], opt.Value)
+		}
+		target[name] = settings
+	}
+	return result
+}
+
+// Add pushes a configuration layer with the given source name on top of the existing layers.
+func (l *Layered) Add(cfg *Config, source string) {
+	l.layers = append(l.layers, cfg)
+	l.sources = append(l.sources, source)
+}
+
+// Resolve returns the effective settings for the given prefix and branch. Within a layer, global
+// defaults are overridden by the prefix settings, which are overridden by matching branch patterns,
+// which are overridden by an exact branch name. Paths of every prefix are always included under
+// the "path" key as <prefix>=<path> entries, so that commands can see the full component mapping.
+func (l *Layered) Resolve(prefix, branch string) map[string]Value {
+	resolved := make(map[string]Value)
+	var paths []string
+	pathsSource := ""
+
+	for i, cfg := range l.layers {
+		source := l.sources[i]
+		apply := func(settings Settings) {
+			for key, values := range settings {
+				if key == PathsKey {
+					continue
+				}
+				resolved[key] = Value{Values: values, Source: source}
+			}
+		}
+
+		apply(cfg.Defaults)
+		if settings, ok := cfg.Prefixes[prefix]; ok {
+			apply(settings)
+		}
+		if branch != "" {
+			patterns := make([]string, 0, len(cfg.Branches))
+			for pattern := range cfg.Branches {
+				patterns = append(patterns, pattern)
+			}
+			sort.Strings(patterns)
+			for _, pattern := range patterns {
+				if pattern == branch {
+					continue
+				}
+				if matched, _ := path.Match(pattern, branch); matched {
+					apply(cfg.Branches[pattern])
+				}
+			}
+			if settings, ok := cfg.Branches[branch]; ok {
+				apply(settings)
+			}
+		}
+
+		for p, settings := range cfg.Prefixes {
+			for _, pattern := range settings[PathsKey] {
+				paths = append(paths, p+"="+pattern)
+				pathsSource = source
+			}
+		}
+	}
+
+	if len(paths) > 0 {
+		sort.Strings(paths)
+		if existing, ok := resolved["path"]; ok {
+			paths = append(existing.Values, paths...)
+		}
+		resolved["path"] = Value{Values: paths, Source: pathsSource}
+	}
+	return resolved
+}
+
+// EnvName returns the environment variable name for a flag.
+func EnvName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
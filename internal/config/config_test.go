@@ -0,0 +1,140 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfigFile = `
+defaults:
+  prefix: services/api
+  annotated: true
+  initial-version: 0.1.0
+prefixes:
+  services/api:
+    paths:
+      - services/api
+      - libs/**
+    increment-type: minor
+  services/web:
+    paths: services/web
+branches:
+  main:
+    push: true
+  "release/*":
+    prerelease: rc
+    push: false
+  release/legacy:
+    prerelease: legacy
+`
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	filePath := filepath.Join(t.TempDir(), FileName)
+	require.NoError(t, os.WriteFile(filePath, []byte(content), 0o644))
+	return filePath
+}
+
+func TestLoadFile(t *testing.T) {
+	t.Run("Missing file", func(t *testing.T) {
+		cfg, err := LoadFile(filepath.Join(t.TempDir(), FileName))
+		require.NoError(t, err)
+		assert.Nil(t, cfg)
+	})
+
+	t.Run("Scalars and lists", func(t *testing.T) {
+		cfg, err := LoadFile(writeConfigFile(t, testConfigFile))
+		require.NoError(t, err)
+		require.NotNil(t, cfg)
+		assert.Equal(t, []string{"true"}, cfg.Defaults["annotated"])
+		assert.Equal(t, []string{"services/api", "libs/**"}, cfg.Prefixes["services/api"][PathsKey])
+		assert.Equal(t, []string{"services/web"}, cfg.Prefixes["services/web"][PathsKey])
+		assert.Equal(t, []string{"rc"}, cfg.Branches["release/*"]["prerelease"])
+	})
+
+	t.Run("Invalid value", func(t *testing.T) {
+		_, err := LoadFile(writeConfigFile(t, "defaults:\n  prefix:\n    nested: value\n"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "'prefix' must be a scalar or a list of scalars")
+	})
+}
+
+func TestFromGitConfig(t *testing.T) {
+	raw := gitconfig.NewConfig()
+	require.NoError(t, raw.Unmarshal([]byte(`
+[semver]
+	initial-version = 1.0.0
+	Annotated = true
+[semver "prefix:services/api"]
+	upstream = fork
+[semver "branch:release/*"]
+	push = true
+[semver "unrelated"]
+	ignored = true
+`)))
+
+	cfg := FromGitConfig(raw)
+	assert.Equal(t, []string{"1.0.0"}, cfg.Defaults["initial-version"])
+	assert.Equal(t, []string{"true"}, cfg.Defaults["annotated"])
+	assert.Equal(t, []string{"fork"}, cfg.Prefixes["services/api"]["upstream"])
+	assert.Equal(t, []string{"true"}, cfg.Branches["release/*"]["push"])
+	assert.Len(t, cfg.Prefixes, 1)
+	assert.Len(t, cfg.Branches, 1)
+}
+
+func TestResolve(t *testing.T) {
+	fileCfg, err := LoadFile(writeConfigFile(t, testConfigFile))
+	require.NoError(t, err)
+
+	gitCfg := &Config{
+		Defaults: Settings{"upstream": {"upstream"}},
+		Branches: map[string]Settings{"main": {"annotated": {"false"}}},
+	}
+
+	layered := &Layered{}
+	layered.Add(fileCfg, SourceConfigFile)
+	layered.Add(gitCfg, SourceGitConfig)
+
+	t.Run("Global defaults and prefix settings", func(t *testing.T) {
+		settings := layered.Resolve("services/api", "")
+		assert.Equal(t, Value{Values: []string{"true"}, Source: SourceConfigFile}, settings["annotated"])
+		assert.Equal(t, Value{Values: []string{"minor"}, Source: SourceConfigFile}, settings["increment-type"])
+		assert.Equal(t, Value{Values: []string{"upstream"}, Source: SourceGitConfig}, settings["upstream"])
+		_, ok := settings[PathsKey]
+		assert.False(t, ok)
+	})
+
+	t.Run("Paths of every prefix", func(t *testing.T) {
+		settings := layered.Resolve("", "")
+		assert.Equal(t, []string{"services/api=libs/**", "services/api=services/api", "services/web=services/web"}, settings["path"].Values)
+		_, ok := settings["increment-type"]
+		assert.False(t, ok)
+	})
+
+	t.Run("Git config overrides the config file", func(t *testing.T) {
+		settings := layered.Resolve("services/api", "main")
+		assert.Equal(t, Value{Values: []string{"false"}, Source: SourceGitConfig}, settings["annotated"])
+		assert.Equal(t, Value{Values: []string{"true"}, Source: SourceConfigFile}, settings["push"])
+	})
+
+	t.Run("Branch patterns", func(t *testing.T) {
+		settings := layered.Resolve("services/api", "release/1.x")
+		assert.Equal(t, []string{"rc"}, settings["prerelease"].Values)
+		assert.Equal(t, []string{"false"}, settings["push"].Values)
+	})
+
+	t.Run("Exact branch name wins over patterns", func(t *testing.T) {
+		settings := layered.Resolve("services/api", "release/legacy")
+		assert.Equal(t, []string{"legacy"}, settings["prerelease"].Values)
+	})
+}
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "SEMVER_GIT_INITIAL_VERSION", EnvName("initial-version"))
+	assert.Equal(t, "SEMVER_GIT_PREFIX", EnvName("prefix"))
+}
@@ -0,0 +1,290 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+// Authentication methods for pushing to and deleting from remotes.
+const (
+	AuthAuto             = "auto"
+	AuthNone             = "none"
+	AuthSSHAgent         = "ssh-agent"
+	AuthSSHKey           = "ssh-key"
+	AuthBasic            = "basic"
+	AuthToken            = "token"
+	AuthCredentialHelper = "credential-helper"
+)
+
+// Environment variables holding secrets, which are never read from flags or configuration files.
+const (
+	EnvToken            = "SEMVER_GIT_TOKEN"
+	EnvPassword         = "SEMVER_GIT_PASSWORD"
+	EnvSSHKeyPassphrase = "SEMVER_GIT_SSH_KEY_PASSPHRASE"
+)
+
+// tokenUsername is the user name sent with a token over HTTP basic auth if none is configured;
+// GitHub, GitLab and Bitbucket only check the token.
+const tokenUsername = "x-access-token"
+
+// AuthOptions configures how to authenticate against a remote.
+type AuthOptions struct {
+	// Method is one of the Auth* constants; AuthAuto picks one based on the remote URL and the options set.
+	Method string
+	// SSHUser overrides the user of an SSH remote URL (default "git").
+	SSHUser          string
+	SSHKeyPath       string
+	SSHKeyPassphrase string
+	// Username and Password are used for HTTP basic auth; Username is also sent with Token.
+	Username string
+	Password string
+	Token    string
+	// GitHubToken is used instead of Token, if that is not set, for remotes on github.com or on the
+	// host of GitHubServerURL only, so that it is never sent to other hosts.
+	GitHubToken     string
+	GitHubServerURL string
+	// Dir is the directory git credential helpers run in, so that repository configuration applies.
+	Dir string
+}
+
+// AuthSecretsFromEnv fills the secrets of opts from the SEMVER_GIT_* environment variables, and the
+// GitHub token and server URL from GITHUB_TOKEN and GITHUB_SERVER_URL, as set by GitHub Actions.
+func AuthSecretsFromEnv(opts AuthOptions, getenv func(string) string) AuthOptions {
+	opts.Token = getenv(EnvToken)
+	opts.GitHubToken = getenv("GITHUB_TOKEN")
+	opts.GitHubServerURL = getenv("GITHUB_SERVER_URL")
+	opts.Password = getenv(EnvPassword)
+	opts.SSHKeyPassphrase = getenv(EnvSSHKeyPassphrase)
+	return opts
+}
+
+// Secrets returns the secret values set in opts, for redaction.
+func (opts AuthOptions) Secrets() []string {
+	var secrets []string
+	for _, s := range []string{opts.Token, opts.GitHubToken, opts.Password, opts.SSHKeyPassphrase} {
+		if s != "" {
+			secrets = append(secrets, s)
+		}
+	}
+	return secrets
+}
+
+// tokenFor returns the token to send to host: Token if set, otherwise GitHubToken if host is a GitHub host.
+func (opts AuthOptions) tokenFor(host string) string {
+	if opts.Token != "" {
+		return opts.Token
+	}
+	if opts.GitHubToken == "" {
+		return ""
+	}
+	if strings.EqualFold(host, "github.com") {
+		return opts.GitHubToken
+	}
+	if server, err := url.Parse(opts.GitHubServerURL); err == nil && server.Hostname() != "" &&
+		strings.EqualFold(host, server.Hostname()) {
+		return opts.GitHubToken
+	}
+	return ""
+}
+
+var urlPasswordPattern = regexp.MustCompile(`(://[^/\s:@]*):[^/\s@]*@`)
+
+// filledPasswords holds the passwords returned by git credential helpers, which RedactSecrets
+// redacts as well, since they are not known from the environment.
+var filledPasswords struct {
+	sync.Mutex
+	values []string
+}
+
+func addFilledPassword(password string) {
+	filledPasswords.Lock()
+	defer filledPasswords.Unlock()
+	filledPasswords.values = append(filledPasswords.values, password)
+}
+
+// RedactSecrets replaces the given secrets, the passwords returned by git credential helpers and any
+// password embedded in a URL with "***".
+func RedactSecrets(s string, secrets ...string) string {
+	filledPasswords.Lock()
+	secrets = append(append([]string(nil), secrets...), filledPasswords.values...)
+	filledPasswords.Unlock()
+	// Longer secrets first, so that a secret contained in another does not break the longer match.
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, "***")
+		}
+	}
+	return urlPasswordPattern.ReplaceAllString(s, "$1:***@")
+}
+
+// RemoteAuth returns the authentication for the first URL of the named remote.
+func RemoteAuth(repo *git.Repository, remoteName string, opts AuthOptions) (transport.AuthMethod, error) {
+	remote, err := repo.Remote(remoteName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find remote '%s': %w", remoteName, err)
+	}
+	urls := remote.Config().URLs
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("remote '%s' has no URL", remoteName)
+	}
+	if opts.Dir == "" {
+		if worktree, err := repo.Worktree(); err == nil {
+			opts.Dir = worktree.Filesystem.Root()
+		}
+	}
+	return ResolveAuth(urls[0], opts)
+}
+
+// ResolveAuth returns the authentication for a remote URL. It returns nil if no authentication is
+// needed or available, in which case go-git falls back to its defaults.
+func ResolveAuth(remoteURL string, opts AuthOptions) (transport.AuthMethod, error) {
+	endpoint, err := transport.NewEndpoint(remoteURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote URL: %w", err)
+	}
+	isSSH := endpoint.Protocol == "ssh"
+	isHTTP := endpoint.Protocol == "http" || endpoint.Protocol == "https"
+	token := opts.tokenFor(endpoint.Host)
+
+	method := opts.Method
+	if method == "" || method == AuthAuto {
+		switch {
+		case isSSH && opts.SSHKeyPath != "":
+			method = AuthSSHKey
+		case isSSH && os.Getenv("SSH_AUTH_SOCK") != "":
+			method = AuthSSHAgent
+		case isHTTP && token != "":
+			method = AuthToken
+		case isHTTP && opts.Username != "" && opts.Password != "":
+			method = AuthBasic
+		case isHTTP:
+			method = AuthCredentialHelper
+		default:
+			method = AuthNone
+		}
+	}
+
+	sshUser := opts.SSHUser
+	if sshUser == "" {
+		sshUser = endpoint.User
+	}
+	if sshUser == "" {
+		sshUser = gitssh.DefaultUsername
+	}
+
+	switch method {
+	case AuthNone:
+		return nil, nil
+	case AuthSSHAgent:
+		auth, err := gitssh.NewSSHAgentAuth(sshUser)
+		if err != nil {
+			return nil, fmt.Errorf("failed to use SSH agent: %w", err)
+		}
+		return auth, nil
+	case AuthSSHKey:
+		if opts.SSHKeyPath == "" {
+			return nil, fmt.Errorf("auth method %s requires an SSH key file", AuthSSHKey)
+		}
+		auth, err := gitssh.NewPublicKeysFromFile(sshUser, opts.SSHKeyPath, opts.SSHKeyPassphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load SSH key %s: %w", opts.SSHKeyPath, err)
+		}
+		return auth, nil
+	case AuthBasic:
+		if opts.Username == "" || opts.Password == "" {
+			return nil, fmt.Errorf("auth method %s requires a username and %s", AuthBasic, EnvPassword)
+		}
+		return &githttp.BasicAuth{Username: opts.Username, Password: opts.Password}, nil
+	case AuthToken:
+		if token == "" {
+			return nil, fmt.Errorf("auth method %s requires %s (GITHUB_TOKEN is only used for GitHub remotes)", AuthToken, EnvToken)
+		}
+		username := opts.Username
+		if username == "" {
+			username = tokenUsername
+		}
+		return &githttp.BasicAuth{Username: username, Password: token}, nil
+	case AuthCredentialHelper:
+		username, password, err := credentialFill(opts.Dir, endpoint, opts.Username)
+		if err != nil {
+			return nil, err
+		}
+		if password == "" {
+			// No helper knows the remote; let go-git try without credentials.
+			return nil, nil
+		}
+		return &githttp.BasicAuth{Username: username, Password: password}, nil
+	default:
+		return nil, fmt.Errorf("invalid auth method '%s': must be one of %s", method,
+			strings.Join([]string{AuthAuto, AuthNone, AuthSSHAgent, AuthSSHKey, AuthBasic, AuthToken, AuthCredentialHelper}, ", "))
+	}
+}
+
+// credentialFill asks the configured git credential helpers for the credentials of an HTTP(S) endpoint,
+// using the `git credential fill` protocol. Interactive prompts are disabled.
+func credentialFill(dir string, endpoint *transport.Endpoint, username string) (string, string, error) {
+	host := endpoint.Host
+	if endpoint.Port != 0 && endpoint.Port != 80 && endpoint.Port != 443 {
+		host = fmt.Sprintf("%s:%d", host, endpoint.Port)
+	}
+	var input bytes.Buffer
+	fmt.Fprintf(&input, "protocol=%s\nhost=%s\npath=%s\n", endpoint.Protocol, host, strings.TrimPrefix(endpoint.Path, "/"))
+	if username == "" {
+		username = endpoint.User
+	}
+	if username != "" {
+		fmt.Fprintf(&input, "username=%s\n", username)
+	}
+	input.WriteString("\n")
+
+	cmd := exec.Command("git", "credential", "fill")
+	cmd.Dir = dir
+	cmd.Stdin = &input
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=", "SSH_ASKPASS=")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		// git credential fill fails this way if no helper provides credentials and prompting is disabled.
+		if strings.Contains(stderr.String(), "terminal prompts disabled") {
+			return "", "", nil
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", "", fmt.Errorf("git credential fill failed: %s", msg)
+		}
+		return "", "", fmt.Errorf("git credential fill failed: %w", err)
+	}
+
+	var password string
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "username":
+			username = value
+		case "password":
+			password = value
+		}
+	}
+	if password != "" {
+		addFilledPassword(password)
+	}
+	return username, password, nil
+}
@@ -0,0 +1,198 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// Names of the backends accepted by NewBackend.
+const (
+	// BackendGoGit implements the git operations in process with go-git.
+	BackendGoGit = "go-git"
+	// BackendGit runs the git binary, which supports partial clones, all worktree layouts and
+	// credential helpers.
+	BackendGit = "git"
+)
+
+// Backend is the git implementation version tags are looked up, created and pushed with.
+type Backend interface {
+	// ResolveRevision returns the object a revision such as HEAD, a branch, tag or commit hash points to.
+	ResolveRevision(rev string) (plumbing.Hash, error)
+	// Commit returns the commit with the given hash or, if hash is an annotated tag, the commit it points to.
+	Commit(hash plumbing.Hash) (*object.Commit, error)
+	// TagObject returns the annotated tag with the given hash, or plumbing.ErrObjectNotFound if there is none.
+	TagObject(hash plumbing.Hash) (*object.Tag, error)
+	// TagRefs returns the tags whose full ref name starts with refPrefix and that point to a commit.
+	TagRefs(refPrefix string) ([]TagRef, error)
+	// CreateTag creates a lightweight tag or, if opts is not nil, an annotated tag. It returns
+	// ErrTagExists if the tag already exists.
+	CreateTag(name string, target plumbing.Hash, opts *git.CreateTagOptions) error
+	// DeleteTag deletes a local tag. It returns git.ErrTagNotFound if the tag does not exist.
+	DeleteTag(name string) error
+	// PushTag pushes a tag to the named remote without moving a tag the remote already has, see PushTag.
+	PushTag(remoteName, tagName string, auth transport.AuthMethod) error
+	// Close releases the resources of the backend.
+	Close() error
+}
+
+// TagRef is a tag peeled to the commit it points to.
+type TagRef struct {
+	// Name is the short name of the tag, e.g. "v1.2.3".
+	Name string
+	// Hash is the object the tag ref points to: the commit, or the tag object of an annotated tag.
+	Hash plumbing.Hash
+	// Commit is the commit the tag points to, and When its committer time.
+	Commit plumbing.Hash
+	When   time.Time
+}
+
+// NewBackend returns the named backend (BackendGoGit or BackendGit) for repo. The git backend
+// requires a repository on disk and the git binary.
+func NewBackend(repo *git.Repository, name string) (Backend, error) {
+	switch name {
+	case BackendGoGit:
+		return NewGoGitBackend(repo), nil
+	case BackendGit:
+		fs, ok := repoFilesystem(repo)
+		if !ok {
+			return nil, errors.New("the git backend requires a repository on disk")
+		}
+		return NewExecBackend(fs.Root())
+	default:
+		return nil, fmt.Errorf("invalid backend '%s': must be '%s' or '%s'", name, BackendGoGit, BackendGit)
+	}
+}
+
+// goGitBackend implements Backend with go-git.
+type goGitBackend struct {
+	repo *git.Repository
+}
+
+// NewGoGitBackend returns the Backend that implements the git operations on repo with go-git.
+func NewGoGitBackend(repo *git.Repository) Backend {
+	return &goGitBackend{repo: repo}
+}
+
+func (b *goGitBackend) ResolveRevision(rev string) (plumbing.Hash, error) {
+	if rev == "HEAD" {
+		headRef, err := b.repo.Head()
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("failed to resolve HEAD: %w", err)
+		}
+		return headRef.Hash(), nil
+	}
+	hash, err := b.repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to resolve git ref '%s': %w", rev, err)
+	}
+	return *hash, nil
+}
+
+func (b *goGitBackend) Commit(hash plumbing.Hash) (*object.Commit, error) {
+	commit, err := b.repo.CommitObject(hash)
+	if err == nil {
+		return commit, nil
+	}
+	tagObj, err := b.repo.TagObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch commit object: %w", err)
+	}
+	commit, err = b.repo.CommitObject(tagObj.Target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch commit object from annotated tag: %w", err)
+	}
+	return commit, nil
+}
+
+func (b *goGitBackend) TagObject(hash plumbing.Hash) (*object.Tag, error) {
+	return b.repo.TagObject(hash)
+}
+
+func (b *goGitBackend) TagRefs(refPrefix string) ([]TagRef, error) {
+	tags, err := b.repo.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve tags: %w", err)
+	}
+	resolver := newTagResolver(b.repo, refPrefix)
+	var refs []TagRef
+	err = tags.ForEach(func(ref *plumbing.Reference) error {
+		// Only refs that can match are peeled.
+		if !strings.HasPrefix(ref.Name().String(), refPrefix) {
+			return nil
+		}
+		commit, when, err := resolver.resolve(ref)
+		if err != nil {
+			return nil
+		}
+		refs = append(refs, TagRef{Name: ref.Name().Short(), Hash: ref.Hash(), Commit: commit, When: when})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+func (b *goGitBackend) CreateTag(name string, target plumbing.Hash, opts *git.CreateTagOptions) error {
+	_, err := b.repo.CreateTag(name, target, opts)
+	return err
+}
+
+func (b *goGitBackend) DeleteTag(name string) error {
+	return b.repo.DeleteTag(name)
+}
+
+func (b *goGitBackend) PushTag(remoteName, tagName string, auth transport.AuthMethod) error {
+	tagRef, err := b.repo.Tag(tagName)
+	if err != nil {
+		return fmt.Errorf("failed to find tag '%s': %w", tagName, err)
+	}
+	remote, err := b.repo.Remote(remoteName)
+	if err != nil {
+		return fmt.Errorf("failed to find remote '%s': %w", remoteName, err)
+	}
+
+	refs, err := remote.List(&git.ListOptions{Auth: auth})
+	if err != nil && !errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		return fmt.Errorf("failed to list remote references: %w", err)
+	}
+	for _, ref := range refs {
+		if ref.Name() == tagRef.Name() {
+			if ref.Hash() == tagRef.Hash() {
+				return nil
+			}
+			return fmt.Errorf("%w: %s", ErrTagExistsOnRemote, tagName)
+		}
+	}
+
+	refName := tagRef.Name().String()
+	err = b.repo.Push(&git.PushOptions{
+		RemoteName: remoteName,
+		RefSpecs:   []config.RefSpec{config.RefSpec(refName + ":" + refName)},
+		Auth:       auth,
+	})
+	if err == nil || errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil
+	}
+	// The tag was created on the remote since it was listed.
+	if strings.Contains(err.Error(), "non-fast-forward") || strings.Contains(err.Error(), "already exists") {
+		return fmt.Errorf("%w: %s", ErrTagExistsOnRemote, tagName)
+	}
+	// The remote reports the status of every ref it refused, e.g. because a hook declined it.
+	if strings.Contains(err.Error(), "command error on") {
+		return fmt.Errorf("%w: %v", ErrPushRejected, err)
+	}
+	return err
+}
+
+func (b *goGitBackend) Close() error {
+	return nil
+}
@@ -0,0 +1,172 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreeng/semver-utils/pkg/semver"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBackend(t *testing.T) {
+	repo, _, err := setupRepo()
+	require.NoError(t, err)
+
+	b, err := NewBackend(repo, BackendGoGit)
+	require.NoError(t, err)
+	assert.NoError(t, b.Close())
+
+	_, err = NewBackend(repo, BackendGit)
+	assert.ErrorContains(t, err, "requires a repository on disk")
+	_, err = NewBackend(repo, "jgit")
+	assert.ErrorContains(t, err, "invalid backend 'jgit'")
+}
+
+func TestBackendDeleteTag(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo *git.Repository, commits []*object.Commit, b Backend) {
+		require.NoError(t, b.CreateTag("v9.0.0", commits[4].Hash, nil))
+		require.NoError(t, b.DeleteTag("v9.0.0"))
+		_, err := repo.Tag("v9.0.0")
+		assert.ErrorIs(t, err, git.ErrTagNotFound)
+		assert.ErrorIs(t, b.DeleteTag("v9.0.0"), git.ErrTagNotFound)
+	})
+}
+
+func TestExecBackendTags(t *testing.T) {
+	repo, commits, err := setupRepoOnDisk(t.TempDir())
+	require.NoError(t, err)
+	b, err := NewBackend(repo, BackendGit)
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, b.Close()) }()
+
+	_, err = createVersionTagWith(b, commits[4], semver.SemVer{Major: 1, Minor: 4}, DefaultTagFormat, "", nil)
+	require.NoError(t, err)
+	_, err = createVersionTagWith(b, commits[3], semver.SemVer{Major: 1, Minor: 4}, DefaultTagFormat, "", nil)
+	assert.ErrorIs(t, err, git.ErrTagExists)
+
+	// Signed tags written by the git binary verify like those written by go-git.
+	private, public := newSigningKey(t, "Release Bot", "release@example.com", "")
+	key, err := LoadSigningKey(SigningKeyOptions{Armored: private})
+	require.NoError(t, err)
+	trustedFile := filepath.Join(t.TempDir(), "trusted.asc")
+	require.NoError(t, os.WriteFile(trustedFile, []byte(public), 0600))
+	trustedKeys, err := LoadTrustedKeys(trustedFile)
+	require.NoError(t, err)
+
+	tag, err := createVersionTagWith(b, commits[3], semver.SemVer{Major: 2}, DefaultTagFormat, "signed", &TagOptions{SignKey: key})
+	require.NoError(t, err)
+	signer, err := VerifyTagSignature(repo, tag, trustedKeys)
+	require.NoError(t, err)
+	assert.Equal(t, "Release Bot <release@example.com>", SignerIdentity(signer))
+
+	message, annotated, err := TagMessage(repo, tag)
+	require.NoError(t, err)
+	assert.True(t, annotated)
+	assert.Equal(t, "Version 2.0.0\n", message)
+
+	found, err := FetchVersionTagContext(context.Background(), nil, FetchOptions{Prefix: "signed", Verify: &SignatureVerification{TrustedKeys: trustedKeys}, Backend: b})
+	require.NoError(t, err)
+	assert.Equal(t, "signed/v2.0.0", found.Name)
+	assert.Equal(t, commits[3].Hash, found.Commit.Hash)
+	assert.NotNil(t, found.Signer)
+	commit := found.Commit
+
+	// Commits read by the git binary can walk their history.
+	reachable, err := commits[3].IsAncestor(commit)
+	require.NoError(t, err)
+	assert.True(t, reachable)
+	count, err := CommitsSince(nil, commit)
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+}
+
+func TestBackendTagRefs(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo *git.Repository, commits []*object.Commit, b Backend) {
+		for _, tag := range []string{"v2.0.0", "v2.0.0-rc.1", "v2.0.00/nested", "api/v2.0.0"} {
+			_, err := repo.CreateTag(tag, commits[4].Hash, nil)
+			require.NoError(t, err)
+		}
+		names := func(refPrefix string) []string {
+			refs, err := b.TagRefs(refPrefix)
+			require.NoError(t, err)
+			var names []string
+			for _, ref := range refs {
+				names = append(names, ref.Name)
+			}
+			return names
+		}
+		assert.ElementsMatch(t, []string{"v2.0.0", "v2.0.0-rc.1", "v2.0.00/nested"}, names("refs/tags/v2.0.0"))
+		assert.ElementsMatch(t, []string{"api/v2.0.0"}, names("refs/tags/api/"))
+	})
+}
+
+func TestExecBackendPushRejected(t *testing.T) {
+	repo, _, err := setupRepoOnDisk(t.TempDir())
+	require.NoError(t, err)
+	b, err := NewBackend(repo, BackendGit)
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, b.Close()) }()
+
+	// The remote declines every push in its pre-receive hook.
+	remoteDir := t.TempDir()
+	_, err = git.PlainInit(remoteDir, true)
+	require.NoError(t, err)
+	hook := filepath.Join(remoteDir, "hooks", "pre-receive")
+	require.NoError(t, os.MkdirAll(filepath.Dir(hook), 0o755))
+	require.NoError(t, os.WriteFile(hook, []byte("#!/bin/sh\necho 'tags are protected' >&2\nexit 1\n"), 0o755))
+	_, err = repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{remoteDir}})
+	require.NoError(t, err)
+
+	err = b.PushTag("origin", "v1.0.0", nil)
+	assert.ErrorIs(t, err, ErrPushRejected)
+	assert.NotErrorIs(t, err, ErrTagExistsOnRemote)
+	assert.ErrorContains(t, err, "pre-receive hook declined")
+}
+
+func TestExecBackendCreateTagRace(t *testing.T) {
+	repo, commits, err := setupRepoOnDisk(t.TempDir())
+	require.NoError(t, err)
+	b, err := NewBackend(repo, BackendGit)
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, b.Close()) }()
+
+	// The tag is created by someone else after CreateTag checked that it does not exist.
+	_, err = repo.CreateTag("v9.0.0", commits[1].Hash, nil)
+	require.NoError(t, err)
+	err = b.(*execBackend).createRef("refs/tags/v9.0.0", commits[4].Hash)
+	assert.ErrorIs(t, err, ErrTagExists)
+	ref, err := repo.Tag("v9.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, commits[1].Hash, ref.Hash())
+}
+
+func TestExecBackendPushWithAuth(t *testing.T) {
+	url := setupHTTPRemote(t, "ci", "s3cret")
+	repo, commits, err := setupRepoOnDisk(t.TempDir())
+	require.NoError(t, err)
+	_, err = repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{url}})
+	require.NoError(t, err)
+	_, err = repo.CreateTag("v9.0.0", commits[4].Hash, nil)
+	require.NoError(t, err)
+	b, err := NewBackend(repo, BackendGit)
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, b.Close()) }()
+
+	// Configuration given in the environment is kept, and the credentials only apply to the remote.
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "credential.helper")
+	t.Setenv("GIT_CONFIG_VALUE_0", "")
+	cmd := remoteCommand(b.(*execBackend).gitDir, "origin", &githttp.BasicAuth{Username: "ci", Password: "s3cret"}, "push")
+	assert.Contains(t, cmd.Env, "GIT_CONFIG_KEY_1=http."+url+".extraHeader")
+	assert.Contains(t, cmd.Env, "GIT_CONFIG_COUNT=2")
+
+	assert.Error(t, b.PushTag("origin", "v9.0.0", &githttp.BasicAuth{Username: "ci", Password: "wrong"}))
+	require.NoError(t, b.PushTag("origin", "v9.0.0", &githttp.BasicAuth{Username: "ci", Password: "s3cret"}))
+}
@@ -0,0 +1,147 @@
+package git
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/coreeng/semver-utils/pkg/semver"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ComponentChange describes the files of a component that changed since its latest version tag.
+// Tag, Version and Commit are empty when the component has never been tagged.
+type ComponentChange struct {
+	Tag     string
+	Version semver.SemVer
+	Commit  *object.Commit
+	Files   []string
+}
+
+// Changed reports whether the component needs a new version: either it has never been tagged,
+// or at least one file matching its paths changed since the latest tag.
+func (c ComponentChange) Changed() bool {
+	return c.Tag == "" || len(c.Files) > 0
+}
+
+// DetectChanges determines, for every prefix in paths, which files matching the prefix's path
+// patterns changed between the prefix's latest version tag (as found by FetchVersionTagContext) and targetCommit.
+func DetectChanges(repo *git.Repository, targetCommit *object.Commit, format TagFormat, paths map[string][]string) (map[string]ComponentChange, error) {
+	changes := make(map[string]ComponentChange, len(paths))
+	for prefix, patterns := range paths {
+		tag, err := latestVersionTag(NewGoGitBackend(repo), targetCommit, FetchOptions{Prefix: prefix, Format: format})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch version tag for prefix '%s': %w", prefix, err)
+		}
+
+		files, err := ChangedFiles(tag.Commit, targetCommit, patterns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to detect changes for prefix '%s': %w", prefix, err)
+		}
+
+		changes[prefix] = ComponentChange{
+			Tag:     tag.Name,
+			Version: tag.Version,
+			Commit:  tag.Commit,
+			Files:   files,
+		}
+	}
+	return changes, nil
+}
+
+// ChangedFiles returns the sorted paths of files that differ between fromCommit and toCommit and
+// match at least one of the given patterns. If fromCommit is nil, every matching file in toCommit is returned.
+//
+// A pattern without glob characters matches the path itself and everything below it, so "services/api"
+// matches "services/api/main.go". Patterns with glob characters support '*' and '?' within a single path
+// segment and '**' across segments, e.g. "services/api/**/*.go".
+func ChangedFiles(fromCommit, toCommit *object.Commit, patterns []string) ([]string, error) {
+	matchers := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		matchers = append(matchers, compilePathPattern(pattern))
+	}
+	matches := func(path string) bool {
+		for _, m := range matchers {
+			if m.MatchString(path) {
+				return true
+			}
+		}
+		return false
+	}
+
+	toTree, err := toCommit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree of commit %s: %w", toCommit.Hash, err)
+	}
+
+	found := make(map[string]struct{})
+	if fromCommit == nil {
+		err = toTree.Files().ForEach(func(f *object.File) error {
+			if matches(f.Name) {
+				found[f.Name] = struct{}{}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files of commit %s: %w", toCommit.Hash, err)
+		}
+	} else {
+		fromTree, err := fromCommit.Tree()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tree of commit %s: %w", fromCommit.Hash, err)
+		}
+		diff, err := fromTree.Diff(toTree)
+		if err != nil {
+			return nil, fmt.Errorf("failed to diff commits %s and %s: %w", fromCommit.Hash, toCommit.Hash, err)
+		}
+		for _, change := range diff {
+			for _, name := range []string{change.From.Name, change.To.Name} {
+				if name != "" && matches(name) {
+					found[name] = struct{}{}
+				}
+			}
+		}
+	}
+
+	files := make([]string, 0, len(found))
+	for name := range found {
+		files = append(files, name)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// compilePathPattern converts a path pattern into an anchored regular expression.
+func compilePathPattern(pattern string) *regexp.Regexp {
+	pattern = strings.Trim(pattern, "/")
+	if !strings.ContainsAny(pattern, "*?") {
+		if pattern == "" || pattern == "." {
+			return regexp.MustCompile(`^`)
+		}
+		return regexp.MustCompile(`^` + regexp.QuoteMeta(pattern) + `(?:/|$)`)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '*' && strings.HasPrefix(pattern[i:], "**/"):
+			sb.WriteString(`(?:.*/)?`)
+			i += 2
+		case c == '*' && strings.HasPrefix(pattern[i:], "**"):
+			sb.WriteString(`.*`)
+			i++
+		case c == '*':
+			sb.WriteString(`[^/]*`)
+		case c == '?':
+			sb.WriteString(`[^/]`)
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString(`(?:/|$)`)
+	return regexp.MustCompile(sb.String())
+}
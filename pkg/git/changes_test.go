@@ -0,0 +1,135 @@
+package git
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupMonorepo creates an in-memory repository where each commit writes the given files.
+func setupMonorepo(t *testing.T, commitFiles ...map[string]string) (*git.Repository, []*object.Commit) {
+	t.Helper()
+	repo, err := git.Init(memory.NewStorage(), memfs.New())
+	require.NoError(t, err)
+	w, err := repo.Worktree()
+	require.NoError(t, err)
+
+	commits := make([]*object.Commit, len(commitFiles))
+	for i, files := range commitFiles {
+		for name, content := range files {
+			f, err := w.Filesystem.Create(name)
+			require.NoError(t, err)
+			_, err = f.Write([]byte(content))
+			require.NoError(t, err)
+			require.NoError(t, f.Close())
+			_, err = w.Add(name)
+			require.NoError(t, err)
+		}
+		hash, err := w.Commit("commit", &git.CommitOptions{
+			Author: &object.Signature{
+				Name:  "test",
+				Email: "test@example.com",
+				When:  time.Unix(int64(100*(i+1)), 0),
+			},
+		})
+		require.NoError(t, err)
+		commits[i], err = repo.CommitObject(hash)
+		require.NoError(t, err)
+	}
+	return repo, commits
+}
+
+func TestCompilePathPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"services/api", "services/api/main.go", true},
+		{"services/api", "services/api", true},
+		{"services/api/", "services/api/main.go", true},
+		{"services/api", "services/api-gateway/main.go", false},
+		{".", "anything/at/all.txt", true},
+		{"services/*/main.go", "services/api/main.go", true},
+		{"services/*/main.go", "services/api/cmd/main.go", false},
+		{"services/**/main.go", "services/api/cmd/main.go", true},
+		{"services/**/main.go", "services/main.go", true},
+		{"**/*.go", "lib/util.go", true},
+		{"**/*.go", "lib/util.txt", false},
+		{"lib/**", "lib/a/b/c", true},
+		{"lib/?.txt", "lib/a.txt", true},
+		{"lib/?.txt", "lib/ab.txt", false},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.match, compilePathPattern(tc.pattern).MatchString(tc.path), "pattern %q on path %q", tc.pattern, tc.path)
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	_, commits := setupMonorepo(t,
+		map[string]string{"api/main.go": "v1", "web/index.html": "v1", "README.md": "v1"},
+		map[string]string{"api/main.go": "v2"},
+		map[string]string{"README.md": "v2"},
+	)
+
+	t.Run("Changed files matching a directory", func(t *testing.T) {
+		files, err := ChangedFiles(commits[0], commits[2], []string{"api"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"api/main.go"}, files)
+	})
+
+	t.Run("No changes for untouched directory", func(t *testing.T) {
+		files, err := ChangedFiles(commits[0], commits[2], []string{"web"})
+		require.NoError(t, err)
+		assert.Empty(t, files)
+	})
+
+	t.Run("Multiple patterns", func(t *testing.T) {
+		files, err := ChangedFiles(commits[1], commits[2], []string{"web/**", "*.md"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"README.md"}, files)
+	})
+
+	t.Run("No previous commit lists all matching files", func(t *testing.T) {
+		files, err := ChangedFiles(nil, commits[0], []string{"web", "api"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"api/main.go", "web/index.html"}, files)
+	})
+}
+
+func TestDetectChanges(t *testing.T) {
+	repo, commits := setupMonorepo(t,
+		map[string]string{"api/main.go": "v1", "web/index.html": "v1"},
+		map[string]string{"api/main.go": "v2"},
+	)
+	_, err := repo.CreateTag("api/v1.0.0", commits[0].Hash, nil)
+	require.NoError(t, err)
+	_, err = repo.CreateTag("web/v1.0.0", commits[0].Hash, nil)
+	require.NoError(t, err)
+
+	changes, err := DetectChanges(repo, commits[1], DefaultTagFormat, map[string][]string{
+		"api":   {"api"},
+		"web":   {"web"},
+		"tools": {"tools"},
+	})
+	require.NoError(t, err)
+	require.Len(t, changes, 3)
+
+	assert.True(t, changes["api"].Changed())
+	assert.Equal(t, "api/v1.0.0", changes["api"].Tag)
+	assert.Equal(t, []string{"api/main.go"}, changes["api"].Files)
+
+	assert.False(t, changes["web"].Changed())
+	assert.Equal(t, "web/v1.0.0", changes["web"].Tag)
+	assert.Equal(t, commits[0].Hash, changes["web"].Commit.Hash)
+
+	// A component that was never tagged always counts as changed.
+	assert.True(t, changes["tools"].Changed())
+	assert.Empty(t, changes["tools"].Tag)
+}
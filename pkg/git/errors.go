@@ -0,0 +1,37 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// Errors that callers can test for with errors.Is. The errors returned by this package wrap them
+// with details, such as the name of the tag.
+var (
+	// ErrNoVersionTag is returned when no version tag matches the prefix and tag format.
+	ErrNoVersionTag = errors.New("no matching version tag found")
+	// ErrTagExists is returned when a version tag cannot be created because a tag of the same name,
+	// or of a version with the same precedence, already exists. It is go-git's git.ErrTagExists.
+	ErrTagExists = git.ErrTagExists
+	// ErrPushRejected is returned when the remote rejects the push of a tag, e.g. because of a hook or
+	// a protected tag rule. ErrTagExistsOnRemote is a rejected push as well.
+	ErrPushRejected = errors.New("push rejected by the remote")
+)
+
+// sentinelError is an error with its own message that wraps a sentinel error.
+type sentinelError struct {
+	msg string
+	err error
+}
+
+func (e *sentinelError) Error() string { return e.msg }
+
+func (e *sentinelError) Unwrap() error { return e.err }
+
+// WrapSentinel returns an error with the formatted message for which errors.Is reports sentinel, so
+// that callers can classify an error by its sentinel while keeping their own message.
+func WrapSentinel(sentinel error, format string, args ...interface{}) error {
+	return &sentinelError{msg: fmt.Sprintf(format, args...), err: sentinel}
+}
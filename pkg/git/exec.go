@@ -0,0 +1,426 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// execBackend implements Backend with the git binary. Objects are read through a long-running
+// git cat-file --batch process.
+type execBackend struct {
+	gitDir string
+
+	mu      sync.Mutex
+	catFile *exec.Cmd
+	stdin   io.WriteCloser
+	stdout  *bufio.Reader
+}
+
+// NewExecBackend returns the Backend that runs the git binary on the repository in gitDir. It must
+// be closed to stop the git process that reads objects.
+func NewExecBackend(gitDir string) (Backend, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("the git backend requires the git binary: %w", err)
+	}
+	b := &execBackend{gitDir: gitDir}
+	b.catFile = gitCommand(gitDir, "cat-file", "--batch")
+	stdin, err := b.catFile.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start git cat-file: %w", err)
+	}
+	stdout, err := b.catFile.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start git cat-file: %w", err)
+	}
+	if err := b.catFile.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start git cat-file: %w", err)
+	}
+	b.stdin, b.stdout = stdin, bufio.NewReader(stdout)
+	return b, nil
+}
+
+// gitCommand returns a git command run on the repository in gitDir, which never prompts for
+// credentials.
+func gitCommand(gitDir string, args ...string) *exec.Cmd {
+	cmd := exec.Command("git", append([]string{"--git-dir", gitDir}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	return cmd
+}
+
+// remoteCommand returns a git command talking to remoteName, a remote or URL. auth is passed on
+// for HTTP basic auth and tokens; otherwise git uses its own credentials and SSH configuration.
+func remoteCommand(gitDir, remoteName string, auth transport.AuthMethod, args ...string) *exec.Cmd {
+	cmd := gitCommand(gitDir, args...)
+	basic, ok := auth.(*githttp.BasicAuth)
+	if !ok {
+		return cmd
+	}
+	// Pass the credentials through the environment rather than the command line, appended to the
+	// configuration already given there and only for the URLs of the remote, so that they are not
+	// sent to other hosts, e.g. on redirects or to submodules.
+	header := "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(basic.Username+":"+basic.Password))
+	count, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	for _, url := range remoteURLs(gitDir, remoteName) {
+		cmd.Env = append(cmd.Env,
+			fmt.Sprintf("GIT_CONFIG_KEY_%d=http.%s.extraHeader", count, url),
+			fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", count, header))
+		count++
+	}
+	cmd.Env = append(cmd.Env, "GIT_CONFIG_COUNT="+strconv.Itoa(count))
+	return cmd
+}
+
+// remoteURLs returns the fetch and push URLs of remoteName, or remoteName itself if it is not a
+// configured remote.
+func remoteURLs(gitDir, remoteName string) []string {
+	var urls []string
+	for _, args := range [][]string{{"remote", "get-url", "--all"}, {"remote", "get-url", "--push", "--all"}} {
+		out, err := runGit(gitCommand(gitDir, append(args, remoteName)...), nil)
+		if err != nil {
+			return []string{remoteName}
+		}
+		for _, url := range strings.Fields(string(out)) {
+			if !slices.Contains(urls, url) {
+				urls = append(urls, url)
+			}
+		}
+	}
+	return urls
+}
+
+// runGit runs cmd with stdin as its input and returns its standard output, also if git fails. The
+// error includes the standard error of git.
+func runGit(cmd *exec.Cmd, stdin []byte) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout.Bytes(), fmt.Errorf("git %s: %v: %s", cmd.Args[3], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+func (b *execBackend) git(args ...string) ([]byte, error) {
+	return runGit(gitCommand(b.gitDir, args...), nil)
+}
+
+func (b *execBackend) ResolveRevision(rev string) (plumbing.Hash, error) {
+	out, err := b.git("rev-parse", "--verify", "--end-of-options", rev)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to resolve git ref '%s': %w", rev, err)
+	}
+	return plumbing.NewHash(strings.TrimSpace(string(out))), nil
+}
+
+func (b *execBackend) Commit(hash plumbing.Hash) (*object.Commit, error) {
+	obj, err := b.object(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch commit object: %w", err)
+	}
+	switch obj.Type() {
+	case plumbing.CommitObject:
+		return object.DecodeCommit(execObjectStorer{b}, obj)
+	case plumbing.TagObject:
+		tag, err := object.DecodeTag(execObjectStorer{b}, obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch commit object from annotated tag: %w", err)
+		}
+		return b.Commit(tag.Target)
+	default:
+		return nil, fmt.Errorf("failed to fetch commit object: %s is a %s", hash, obj.Type())
+	}
+}
+
+func (b *execBackend) TagObject(hash plumbing.Hash) (*object.Tag, error) {
+	obj, err := b.object(hash)
+	if err != nil {
+		return nil, err
+	}
+	if obj.Type() != plumbing.TagObject {
+		return nil, plumbing.ErrObjectNotFound
+	}
+	return object.DecodeTag(execObjectStorer{b}, obj)
+}
+
+// tagRefFormat is the for-each-ref format of the fields parsed by TagRefs.
+const tagRefFormat = "%(refname) %(objectname) %(objecttype) %(committerdate:unix) %(*objectname) %(*objecttype) %(*committerdate:unix)"
+
+func (b *execBackend) TagRefs(refPrefix string) ([]TagRef, error) {
+	// The glob "*" does not match slashes, so the refs below refPrefix are matched by "*/**". Ref names
+	// cannot contain glob characters, so refPrefix matches itself, e.g. a single tag looked up by name.
+	out, err := b.git("for-each-ref", "--format="+tagRefFormat, refPrefix+"*", refPrefix+"*/**")
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve tags: %w", err)
+	}
+	var refs []TagRef
+	// Lines end in spaces if the fields of the peeled object are empty, so only newlines are trimmed.
+	for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+		fields := strings.Split(line, " ")
+		if len(fields) != 7 || !strings.HasPrefix(fields[0], refPrefix) {
+			continue
+		}
+		ref := TagRef{Name: plumbing.ReferenceName(fields[0]).Short(), Hash: plumbing.NewHash(fields[1])}
+		var seconds string
+		switch {
+		case fields[2] == "commit":
+			ref.Commit, seconds = ref.Hash, fields[3]
+		case fields[5] == "commit":
+			ref.Commit, seconds = plumbing.NewHash(fields[4]), fields[6]
+		case fields[2] == "tag":
+			// A tag of a tag is peeled one level at a time.
+			commit, err := b.Commit(ref.Hash)
+			if err != nil {
+				continue
+			}
+			ref.Commit, ref.When = commit.Hash, commit.Committer.When
+		default:
+			continue
+		}
+		if seconds != "" {
+			unix, err := strconv.ParseInt(seconds, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse commit time of tag '%s': %w", ref.Name, err)
+			}
+			ref.When = time.Unix(unix, 0)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+func (b *execBackend) CreateTag(name string, target plumbing.Hash, opts *git.CreateTagOptions) error {
+	refName := plumbing.NewTagReferenceName(name).String()
+	if _, err := b.git("rev-parse", "--verify", "--quiet", refName); err == nil {
+		return git.ErrTagExists
+	}
+
+	hash := target
+	if opts != nil {
+		obj, err := b.object(target)
+		if err != nil {
+			return err
+		}
+		if opts.Tagger == nil {
+			return errors.New("annotated tags require a tagger")
+		}
+		if strings.TrimSpace(opts.Message) == "" {
+			return git.ErrMissingMessage
+		}
+		tag := &object.Tag{
+			Name:       name,
+			Tagger:     *opts.Tagger,
+			Message:    strings.TrimSpace(opts.Message) + "\n",
+			TargetType: obj.Type(),
+			Target:     target,
+		}
+		if opts.SignKey != nil {
+			if tag.PGPSignature, err = signTag(tag, opts.SignKey); err != nil {
+				return fmt.Errorf("failed to sign tag: %w", err)
+			}
+		}
+		encoded := &plumbing.MemoryObject{}
+		if err := tag.Encode(encoded); err != nil {
+			return err
+		}
+		if hash, err = (execObjectStorer{b}).SetEncodedObject(encoded); err != nil {
+			return err
+		}
+	}
+	return b.createRef(refName, hash)
+}
+
+// createRef creates the ref refName pointing to hash. The empty old value makes git refuse to
+// overwrite a ref created concurrently, which is reported as git.ErrTagExists.
+func (b *execBackend) createRef(refName string, hash plumbing.Hash) error {
+	_, err := b.git("update-ref", refName, hash.String(), "")
+	if err != nil && strings.Contains(err.Error(), "reference already exists") {
+		return fmt.Errorf("%w: %v", git.ErrTagExists, err)
+	}
+	return err
+}
+
+func (b *execBackend) DeleteTag(name string) error {
+	refName := plumbing.NewTagReferenceName(name).String()
+	if _, err := b.git("rev-parse", "--verify", "--quiet", refName); err != nil {
+		return git.ErrTagNotFound
+	}
+	_, err := b.git("update-ref", "-d", refName)
+	return err
+}
+
+func (b *execBackend) PushTag(remoteName, tagName string, auth transport.AuthMethod) error {
+	refName := plumbing.NewTagReferenceName(tagName).String()
+	local, err := b.ResolveRevision(refName)
+	if err != nil {
+		return fmt.Errorf("failed to find tag '%s': %w", tagName, err)
+	}
+
+	out, err := runGit(remoteCommand(b.gitDir, remoteName, auth, "ls-remote", "--tags", remoteName, refName), nil)
+	if err != nil {
+		return fmt.Errorf("failed to list remote references: %w", err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		hash, name, ok := strings.Cut(line, "\t")
+		if ok && name == refName {
+			if plumbing.NewHash(hash) == local {
+				return nil
+			}
+			return fmt.Errorf("%w: %s", ErrTagExistsOnRemote, tagName)
+		}
+	}
+
+	out, err = runGit(remoteCommand(b.gitDir, remoteName, auth, "push", "--porcelain", remoteName, refName+":"+refName), nil)
+	if err == nil {
+		return nil
+	}
+	// The porcelain status of the ref is printed to stdout, the messages of the remote to stderr.
+	status := string(out) + err.Error()
+	// The tag was created on the remote since it was listed.
+	if strings.Contains(status, "already exists") || strings.Contains(status, "[rejected]") {
+		return fmt.Errorf("%w: %s", ErrTagExistsOnRemote, tagName)
+	}
+	// A ref the remote refused, e.g. because a hook declined it, is reported with the reason:
+	// "!\t<refspec>\t[remote rejected] (pre-receive hook declined)".
+	if _, reason, ok := strings.Cut(status, "[remote rejected]"); ok {
+		reason, _, _ = strings.Cut(reason, "\n")
+		return fmt.Errorf("%w: %s", ErrPushRejected, strings.Trim(reason, " ()"))
+	}
+	return err
+}
+
+func (b *execBackend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_ = b.stdin.Close()
+	return b.catFile.Wait()
+}
+
+// object reads an object with git cat-file.
+func (b *execBackend) object(hash plumbing.Hash) (plumbing.EncodedObject, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, err := fmt.Fprintln(b.stdin, hash.String()); err != nil {
+		return nil, fmt.Errorf("failed to read object %s: %w", hash, err)
+	}
+	header, err := b.stdout.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object %s: %w", hash, err)
+	}
+	// The header is "<hash> <type> <size>", or "<hash> missing".
+	fields := strings.Fields(header)
+	if len(fields) != 3 {
+		return nil, plumbing.ErrObjectNotFound
+	}
+	objType, err := plumbing.ParseObjectType(fields[1])
+	if err != nil {
+		return nil, err
+	}
+	size, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object %s: %w", hash, err)
+	}
+	content := make([]byte, size+1)
+	if _, err := io.ReadFull(b.stdout, content); err != nil {
+		return nil, fmt.Errorf("failed to read object %s: %w", hash, err)
+	}
+	obj := &plumbing.MemoryObject{}
+	obj.SetType(objType)
+	if _, err := obj.Write(content[:size]); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// signTag returns the armored detached signature of tag, as git tag -s writes it.
+func signTag(tag *object.Tag, key *openpgp.Entity) (string, error) {
+	encoded := &plumbing.MemoryObject{}
+	if err := tag.Encode(encoded); err != nil {
+		return "", err
+	}
+	reader, err := encoded.Reader()
+	if err != nil {
+		return "", err
+	}
+	var signature bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&signature, key, reader, nil); err != nil {
+		return "", err
+	}
+	return signature.String(), nil
+}
+
+// execObjectStorer is the object storage of the commits and tags read by execBackend, so that
+// their parents and targets can be read as well.
+type execObjectStorer struct {
+	b *execBackend
+}
+
+func (s execObjectStorer) NewEncodedObject() plumbing.EncodedObject {
+	return &plumbing.MemoryObject{}
+}
+
+func (s execObjectStorer) SetEncodedObject(obj plumbing.EncodedObject) (plumbing.Hash, error) {
+	reader, err := obj.Reader()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	out, err := runGit(gitCommand(s.b.gitDir, "hash-object", "-w", "-t", obj.Type().String(), "--stdin"), content)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to write object: %w", err)
+	}
+	return plumbing.NewHash(strings.TrimSpace(string(out))), nil
+}
+
+func (s execObjectStorer) EncodedObject(objType plumbing.ObjectType, hash plumbing.Hash) (plumbing.EncodedObject, error) {
+	obj, err := s.b.object(hash)
+	if err != nil {
+		return nil, err
+	}
+	if objType != plumbing.AnyObject && obj.Type() != objType {
+		return nil, plumbing.ErrObjectNotFound
+	}
+	return obj, nil
+}
+
+func (s execObjectStorer) IterEncodedObjects(plumbing.ObjectType) (storer.EncodedObjectIter, error) {
+	return nil, errors.New("iterating objects is not supported by the git backend")
+}
+
+func (s execObjectStorer) HasEncodedObject(hash plumbing.Hash) error {
+	_, err := s.b.object(hash)
+	return err
+}
+
+func (s execObjectStorer) EncodedObjectSize(hash plumbing.Hash) (int64, error) {
+	obj, err := s.b.object(hash)
+	if err != nil {
+		return 0, err
+	}
+	return obj.Size(), nil
+}
+
+func (s execObjectStorer) AddAlternate(string) error {
+	return errors.New("adding alternates is not supported by the git backend")
+}
@@ -0,0 +1,193 @@
+package git
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/coreeng/semver-utils/pkg/semver"
+)
+
+const (
+	prefixPlaceholder  = "{prefix}"
+	versionPlaceholder = "{version}"
+	prefixSeparators   = "/-_.@"
+)
+
+// tagFormatPresets are named tag formats that can be used in place of a template.
+var tagFormatPresets = map[string]string{
+	"default": "{prefix}/v{version}",
+	"no-v":    "{prefix}/{version}",
+}
+
+// versionPattern is FullPattern without anchors and without the optional leading 'v'.
+var versionPattern = strings.TrimSuffix(strings.TrimPrefix(semver.FullPattern.String(), "^v?"), "$")
+
+// TagFormat is a tag name template used both to create version tags and to match existing ones.
+// The template must contain {version} exactly once and may contain {prefix} at most once, e.g.
+// "{prefix}/v{version}" (the default), "{prefix}-{version}" or "v{version}-{prefix}".
+//
+// When the prefix is empty, {prefix} is removed together with one adjacent separator ('/', '-', '_',
+// '.' or '@'), so the default format yields "v1.2.3". When matching, a 'v' directly before {version}
+// is optional, so the default format also matches "1.2.3" and "<prefix>/1.2.3".
+type TagFormat struct {
+	template string
+}
+
+// DefaultTagFormat is the <prefix>/v<semver> tag layout.
+var DefaultTagFormat = TagFormat{template: tagFormatPresets["default"]}
+
+// ParseTagFormat validates a tag name template. The preset names "default" and "no-v"
+// ("{prefix}/{version}") are accepted as well.
+func ParseTagFormat(format string) (TagFormat, error) {
+	if preset, ok := tagFormatPresets[format]; ok {
+		format = preset
+	}
+	if strings.Count(format, versionPlaceholder) != 1 {
+		return TagFormat{}, fmt.Errorf("invalid tag format '%s': must contain %s exactly once", format, versionPlaceholder)
+	}
+	if strings.Count(format, prefixPlaceholder) > 1 {
+		return TagFormat{}, fmt.Errorf("invalid tag format '%s': must contain %s at most once", format, prefixPlaceholder)
+	}
+	return TagFormat{template: format}, nil
+}
+
+// String returns the template of the tag format.
+func (f TagFormat) String() string {
+	if f.template == "" {
+		return DefaultTagFormat.template
+	}
+	return f.template
+}
+
+// Format returns the tag name for the given prefix and version.
+func (f TagFormat) Format(prefix string, version semver.SemVer) (string, error) {
+	layout, err := f.layout(prefix)
+	if err != nil {
+		return "", err
+	}
+	return strings.Replace(layout, versionPlaceholder, version.String(), 1), nil
+}
+
+// Match reports whether tagName is a version tag for the given prefix and returns its version.
+func (f TagFormat) Match(tagName, prefix string) (semver.SemVer, bool, error) {
+	layout, err := f.layout(prefix)
+	if err != nil {
+		return semver.SemVer{}, false, err
+	}
+	_, version, ok := matchLayout(compileLayout(layout), tagName)
+	return version, ok, nil
+}
+
+// Parse splits tagName into its prefix and version if it matches the tag format with any prefix,
+// including the empty prefix. Matches with a non-empty prefix take precedence.
+func (f TagFormat) Parse(tagName string) (string, semver.SemVer, bool) {
+	template := f.String()
+	if strings.Contains(template, prefixPlaceholder) {
+		if prefix, version, ok := matchLayout(compileLayout(template), tagName); ok {
+			return prefix, version, true
+		}
+	}
+	layout, _ := f.layout("")
+	return matchLayout(compileLayout(layout), tagName)
+}
+
+// matcher compiles the tag format for repeated matching against a known prefix.
+func (f TagFormat) matcher(prefix string) (*regexp.Regexp, error) {
+	layout, err := f.layout(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return compileLayout(layout), nil
+}
+
+// literalPrefix returns the text every tag name matching the tag format for prefix starts with, so
+// that other tags can be skipped without running the regular expression.
+func (f TagFormat) literalPrefix(prefix string) (string, error) {
+	layout, err := f.layout(prefix)
+	if err != nil {
+		return "", err
+	}
+	literal, _, _ := strings.Cut(layout, versionPlaceholder)
+	// A 'v' directly before {version} is optional.
+	return strings.TrimSuffix(literal, "v"), nil
+}
+
+// layout substitutes the prefix into the template.
+func (f TagFormat) layout(prefix string) (string, error) {
+	template := f.String()
+	idx := strings.Index(template, prefixPlaceholder)
+	if idx < 0 {
+		if prefix != "" {
+			return "", fmt.Errorf("tag format '%s' has no %s placeholder but prefix '%s' was given", template, prefixPlaceholder, prefix)
+		}
+		return template, nil
+	}
+	if prefix != "" {
+		return template[:idx] + prefix + template[idx+len(prefixPlaceholder):], nil
+	}
+
+	before, after := template[:idx], template[idx+len(prefixPlaceholder):]
+	if after != "" && strings.IndexByte(prefixSeparators, after[0]) >= 0 {
+		after = after[1:]
+	} else if before != "" && strings.IndexByte(prefixSeparators, before[len(before)-1]) >= 0 {
+		before = before[:len(before)-1]
+	}
+	return before + after, nil
+}
+
+// compileLayout converts a layout into an anchored regular expression with a "version" group
+// and, if the layout still contains {prefix}, a "prefix" group.
+func compileLayout(layout string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("^")
+	rest := layout
+	for rest != "" {
+		pIdx := strings.Index(rest, prefixPlaceholder)
+		vIdx := strings.Index(rest, versionPlaceholder)
+		next, placeholder := len(rest), ""
+		if pIdx >= 0 && pIdx < next {
+			next, placeholder = pIdx, prefixPlaceholder
+		}
+		if vIdx >= 0 && vIdx < next {
+			next, placeholder = vIdx, versionPlaceholder
+		}
+
+		literal := rest[:next]
+		if placeholder == versionPlaceholder && strings.HasSuffix(literal, "v") {
+			sb.WriteString(regexp.QuoteMeta(strings.TrimSuffix(literal, "v")) + "v?")
+		} else {
+			sb.WriteString(regexp.QuoteMeta(literal))
+		}
+
+		switch placeholder {
+		case prefixPlaceholder:
+			sb.WriteString(`(?P<prefix>.+?)`)
+			rest = rest[next+len(prefixPlaceholder):]
+		case versionPlaceholder:
+			sb.WriteString(`(?P<version>` + versionPattern + `)`)
+			rest = rest[next+len(versionPlaceholder):]
+		default:
+			rest = ""
+		}
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
+
+// matchLayout matches tagName against a compiled layout and parses the captured prefix and version.
+func matchLayout(re *regexp.Regexp, tagName string) (string, semver.SemVer, bool) {
+	matches := re.FindStringSubmatch(tagName)
+	if matches == nil {
+		return "", semver.SemVer{}, false
+	}
+	prefix := ""
+	if idx := re.SubexpIndex("prefix"); idx >= 0 {
+		prefix = matches[idx]
+	}
+	version, err := semver.Parse(matches[re.SubexpIndex("version")])
+	if err != nil {
+		return "", semver.SemVer{}, false
+	}
+	return prefix, version, true
+}
@@ -0,0 +1,157 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/coreeng/semver-utils/pkg/semver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTagFormat(t *testing.T) {
+	t.Run("Presets", func(t *testing.T) {
+		f, err := ParseTagFormat("default")
+		require.NoError(t, err)
+		assert.Equal(t, "{prefix}/v{version}", f.String())
+
+		f, err = ParseTagFormat("no-v")
+		require.NoError(t, err)
+		assert.Equal(t, "{prefix}/{version}", f.String())
+	})
+
+	t.Run("Zero value is the default format", func(t *testing.T) {
+		assert.Equal(t, DefaultTagFormat.String(), TagFormat{}.String())
+	})
+
+	t.Run("Missing version placeholder", func(t *testing.T) {
+		_, err := ParseTagFormat("{prefix}/latest")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "must contain {version} exactly once")
+	})
+
+	t.Run("Repeated prefix placeholder", func(t *testing.T) {
+		_, err := ParseTagFormat("{prefix}/{prefix}-{version}")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "must contain {prefix} at most once")
+	})
+}
+
+func TestTagFormatFormat(t *testing.T) {
+	version, err := semver.Parse("1.2.3-rc.1")
+	require.NoError(t, err)
+
+	tests := []struct {
+		format   string
+		prefix   string
+		expected string
+	}{
+		{"{prefix}/v{version}", "api", "api/v1.2.3-rc.1"},
+		{"{prefix}/v{version}", "", "v1.2.3-rc.1"},
+		{"{prefix}/{version}", "release", "release/1.2.3-rc.1"},
+		{"{prefix}-{version}", "api", "api-1.2.3-rc.1"},
+		{"{prefix}-{version}", "", "1.2.3-rc.1"},
+		{"v{version}-{prefix}", "api", "v1.2.3-rc.1-api"},
+		{"v{version}-{prefix}", "", "v1.2.3-rc.1"},
+		{"v{version}", "", "v1.2.3-rc.1"},
+	}
+	for _, tc := range tests {
+		f, err := ParseTagFormat(tc.format)
+		require.NoError(t, err)
+		tagName, err := f.Format(tc.prefix, version)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, tagName, "format %q with prefix %q", tc.format, tc.prefix)
+	}
+
+	t.Run("Prefix without placeholder", func(t *testing.T) {
+		f, err := ParseTagFormat("v{version}")
+		require.NoError(t, err)
+		_, err = f.Format("api", version)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "has no {prefix} placeholder")
+	})
+}
+
+func TestTagFormatMatch(t *testing.T) {
+	tests := []struct {
+		format  string
+		tagName string
+		prefix  string
+		version string
+		ok      bool
+	}{
+		{"{prefix}/v{version}", "api/v1.2.3", "api", "1.2.3", true},
+		{"{prefix}/v{version}", "api/1.2.3", "api", "1.2.3", true},
+		{"{prefix}/v{version}", "v1.2.3", "", "1.2.3", true},
+		{"{prefix}/v{version}", "1.2.3", "", "1.2.3", true},
+		{"{prefix}/v{version}", "api/v1.2.3", "", "", false},
+		{"{prefix}/v{version}", "other/v1.2.3", "api", "", false},
+		{"{prefix}/v{version}", "api/v1.2", "api", "", false},
+		{"{prefix}/{version}", "release/1.2.3", "release", "1.2.3", true},
+		{"{prefix}/{version}", "release/v1.2.3", "release", "", false},
+		{"{prefix}-{version}", "api-1.2.3", "api", "1.2.3", true},
+		{"{prefix}-{version}", "my-api-1.2.3", "my-api", "1.2.3", true},
+		{"v{version}-{prefix}", "v1.2.3-api", "api", "1.2.3", true},
+		{"v{version}-{prefix}", "v1.2.3-rc.1-api", "api", "1.2.3-rc.1", true},
+		{"v{version}-{prefix}", "v1.2.3-web", "api", "", false},
+	}
+	for _, tc := range tests {
+		f, err := ParseTagFormat(tc.format)
+		require.NoError(t, err)
+		version, ok, err := f.Match(tc.tagName, tc.prefix)
+		require.NoError(t, err)
+		assert.Equal(t, tc.ok, ok, "format %q, tag %q, prefix %q", tc.format, tc.tagName, tc.prefix)
+		if tc.ok {
+			assert.Equal(t, tc.version, version.String())
+		}
+	}
+}
+
+func TestTagFormatParse(t *testing.T) {
+	tests := []struct {
+		format  string
+		tagName string
+		prefix  string
+		version string
+		ok      bool
+	}{
+		{"{prefix}/v{version}", "services/api/v1.2.3", "services/api", "1.2.3", true},
+		{"{prefix}/v{version}", "v1.2.3", "", "1.2.3", true},
+		{"{prefix}/v{version}", "pre/non-semver", "", "", false},
+		{"{prefix}-{version}", "my-api-1.2.3", "my-api", "1.2.3", true},
+		{"{prefix}-{version}", "1.2.3", "", "1.2.3", true},
+		{"v{version}-{prefix}", "v1.2.3-api", "api", "1.2.3", true},
+		{"{prefix}/{version}", "release/1.2.3", "release", "1.2.3", true},
+	}
+	for _, tc := range tests {
+		f, err := ParseTagFormat(tc.format)
+		require.NoError(t, err)
+		prefix, version, ok := f.Parse(tc.tagName)
+		assert.Equal(t, tc.ok, ok, "format %q, tag %q", tc.format, tc.tagName)
+		if tc.ok {
+			assert.Equal(t, tc.prefix, prefix)
+			assert.Equal(t, tc.version, version.String())
+		}
+	}
+}
+
+func TestTagFormatLiteralPrefix(t *testing.T) {
+	tests := []struct {
+		format   string
+		prefix   string
+		expected string
+	}{
+		{"{prefix}/v{version}", "api", "api/"},
+		{"{prefix}/v{version}", "", ""},
+		{"{prefix}/{version}", "release", "release/"},
+		{"{prefix}-{version}", "api", "api-"},
+		{"v{version}-{prefix}", "api", ""},
+		{"release-v{version}", "", "release-"},
+	}
+	for _, tc := range tests {
+		f, err := ParseTagFormat(tc.format)
+		require.NoError(t, err)
+		literal, err := f.literalPrefix(tc.prefix)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, literal, "format %s, prefix %s", tc.format, tc.prefix)
+	}
+}
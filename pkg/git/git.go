@@ -1,153 +1,458 @@
 package git
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"os"
+	"regexp"
+	"sort"
 	"strings"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/coreeng/semver-utils/pkg/semver"
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 )
 
 // FetchCommitObject retrieves the commit associated with the given Git reference.
 // It accepts HEAD, branch, tag, commit hash, or annotated tag.
 func FetchCommitObject(repo *git.Repository, gitRef string) (*object.Commit, error) {
-	var hash plumbing.Hash
-	var err error
+	return fetchCommitObject(NewGoGitBackend(repo), gitRef)
+}
 
-	if gitRef == "HEAD" {
-		headRef, err := repo.Head()
-		if err != nil {
-			return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
-		}
-		hash = headRef.Hash()
-	} else {
-		hashPtr, err := repo.ResolveRevision(plumbing.Revision(gitRef))
-		if err != nil {
-			return nil, fmt.Errorf("failed to resolve git ref '%s': %w", gitRef, err)
+func fetchCommitObject(b Backend, gitRef string) (*object.Commit, error) {
+	hash, err := b.ResolveRevision(gitRef)
+	if err != nil {
+		return nil, err
+	}
+	return b.Commit(hash)
+}
+
+// CurrentBranch returns the short name of the branch HEAD points to, or an empty string if HEAD is detached.
+func CurrentBranch(repo *git.Repository) (string, error) {
+	headRef, err := repo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	if headRef.Type() != plumbing.SymbolicReference || !headRef.Target().IsBranch() {
+		return "", nil
+	}
+	return headRef.Target().Short(), nil
+}
+
+// ciBranchEnvVars are the environment variables CI systems use to expose the branch being built,
+// in order of precedence. Pull/merge request source branches come before the target branch.
+var ciBranchEnvVars = []string{
+	"GITHUB_HEAD_REF",
+	"CI_MERGE_REQUEST_SOURCE_BRANCH_NAME",
+	"CI_COMMIT_BRANCH",
+	"BITBUCKET_BRANCH",
+	"BUILDKITE_BRANCH",
+	"CIRCLE_BRANCH",
+	"TRAVIS_PULL_REQUEST_BRANCH",
+	"TRAVIS_BRANCH",
+	"BRANCH_NAME",
+	"GIT_BRANCH",
+}
+
+// BranchFromEnv returns the branch being built according to common CI environment variables
+// (GitHub Actions, GitLab CI, Bitbucket Pipelines, Buildkite, CircleCI, Travis CI and Jenkins),
+// or an empty string if none is set.
+func BranchFromEnv(getenv func(string) string) string {
+	for _, name := range ciBranchEnvVars {
+		if branch := getenv(name); branch != "" {
+			return strings.TrimPrefix(branch, "origin/")
 		}
-		hash = *hashPtr
 	}
+	if getenv("GITHUB_REF_TYPE") == "branch" {
+		return getenv("GITHUB_REF_NAME")
+	}
+	return ""
+}
 
-	commit, err := repo.CommitObject(hash)
-	if err == nil {
-		return commit, nil
+// DetectBranch returns the current branch from HEAD or, if HEAD is detached as is common in CI,
+// from the CI environment variables. It returns an empty string if no branch can be determined.
+func DetectBranch(repo *git.Repository) (string, error) {
+	branch, err := CurrentBranch(repo)
+	if err != nil || branch != "" {
+		return branch, err
 	}
+	return BranchFromEnv(os.Getenv), nil
+}
 
-	tagObj, err := repo.TagObject(hash)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch commit object: %w", err)
+// CommitsSince counts the commits reachable from toCommit that are not reachable from fromCommit.
+// If fromCommit is nil, all commits reachable from toCommit are counted.
+func CommitsSince(fromCommit, toCommit *object.Commit) (int, error) {
+	commits, err := CommitLog(fromCommit, toCommit)
+	return len(commits), err
+}
+
+// CommitLog returns the commits reachable from toCommit that are not reachable from fromCommit,
+// starting with toCommit. If fromCommit is nil, all commits reachable from toCommit are returned.
+func CommitLog(fromCommit, toCommit *object.Commit) ([]*object.Commit, error) {
+	seen := make(map[plumbing.Hash]bool)
+	if fromCommit != nil {
+		err := object.NewCommitPreorderIter(fromCommit, nil, nil).ForEach(func(c *object.Commit) error {
+			seen[c.Hash] = true
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk history of commit %s: %w", fromCommit.Hash, err)
+		}
 	}
-	commit, err = repo.CommitObject(tagObj.Target)
+
+	var commits []*object.Commit
+	err := object.NewCommitPreorderIter(toCommit, seen, nil).ForEach(func(c *object.Commit) error {
+		commits = append(commits, c)
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch commit object from annotated tag: %w", err)
+		return nil, fmt.Errorf("failed to walk history of commit %s: %w", toCommit.Hash, err)
 	}
-	return commit, nil
+	return commits, nil
 }
 
 // FetchVersionTag searches for a semantic version tag in the repository that matches the specified prefix.
 // If exactCommit is true, only tags pointing exactly to targetCommit are considered.
 // Otherwise, it selects the most recent tag from the commit history not after targetCommit.
+// If no tag matches, the tag name is empty and the error nil; FetchVersionTagContext returns
+// ErrNoVersionTag instead, and supports other tag formats and backends.
 func FetchVersionTag(repo *git.Repository, targetCommit *object.Commit, prefix string, exactCommit bool) (string, semver.SemVer, *object.Commit, error) {
-	tags, err := repo.Tags()
+	tag, err := latestVersionTag(NewGoGitBackend(repo), targetCommit, FetchOptions{Prefix: prefix, ExactCommit: exactCommit})
+	return tag.Name, tag.Version, tag.Commit, err
+}
+
+// latestVersionTag is fetchVersionTag, returning the zero VersionTag instead of ErrNoVersionTag.
+func latestVersionTag(b Backend, targetCommit *object.Commit, opts FetchOptions) (VersionTag, error) {
+	tag, err := fetchVersionTag(context.Background(), b, targetCommit, opts)
+	if errors.Is(err, ErrNoVersionTag) {
+		return VersionTag{}, nil
+	}
+	return tag, err
+}
+
+// CreateVersionTag creates a new Git tag for the given targetCommit with the specified semantic version.
+// It constructs the new tag name using an optional prefix. If annotated is true, the tag will include
+// a message and tagger information. It returns the new tag name or an error.
+// CreateVersionTagContext supports other tag formats, messages, taggers, signatures and backends.
+func CreateVersionTag(repo *git.Repository, targetCommit *object.Commit, version semver.SemVer, prefix string, annotated bool) (string, error) {
+	var annotation *TagOptions
+	if annotated {
+		annotation = &TagOptions{}
+	}
+	tag, err := createVersionTag(context.Background(), NewGoGitBackend(repo), targetCommit, CreateOptions{Prefix: prefix, Version: version, Annotation: annotation})
 	if err != nil {
-		return "", semver.SemVer{}, nil, fmt.Errorf("failed to retrieve tags: %w", err)
+		return "", err
 	}
+	return tag.Name, nil
+}
 
-	commitCache := make(map[string]*object.Commit)
-	var foundTag string
-	var foundVersion semver.SemVer
-	var foundCommit *object.Commit
-	targetTime := targetCommit.Committer.When
+// TagOptions configures an annotated version tag, see CreateOptions.
+type TagOptions struct {
+	// Message is the tag message; an empty message is replaced by "Version <version>".
+	Message string
+	// Tagger is the identity and date of the tag; the committer of the target commit is used if nil.
+	Tagger *object.Signature
+	// SignKey is a decrypted OpenPGP private key the tag is signed with (see LoadSigningKey), or nil.
+	SignKey *openpgp.Entity
+}
+
+// TagInfo describes a tag ref and, for an annotated tag, its tag object.
+type TagInfo struct {
+	// Name is the short name of the tag, e.g. "v1.2.3".
+	Name string
+	// Hash is the object the tag ref points to: the tag object of an annotated tag, otherwise the commit.
+	Hash plumbing.Hash
+	// Object is the tag object of an annotated tag, or nil for a lightweight tag.
+	Object *object.Tag
+}
 
-	err = tags.ForEach(func(ref *plumbing.Reference) error {
-		candidateTagName := ref.Name().Short()
-		var versionString string
+// Annotated reports whether the tag is an annotated tag.
+func (t TagInfo) Annotated() bool {
+	return t.Object != nil
+}
 
-		if prefix != "" {
-			tagPrefix := prefix + "/"
-			if !strings.HasPrefix(candidateTagName, tagPrefix) {
-				return nil
-			}
-			versionString = strings.TrimPrefix(candidateTagName, tagPrefix)
-		} else {
-			versionString = candidateTagName
+// FetchTagInfo looks up the tag with the given short name with b.
+func FetchTagInfo(b Backend, tagName string) (TagInfo, error) {
+	// ResolveRevision may peel annotated tags, so the tag object is looked up among the tag refs.
+	refs, err := b.TagRefs(plumbing.NewTagReferenceName(tagName).String())
+	if err != nil {
+		return TagInfo{}, fmt.Errorf("failed to find tag '%s': %w", tagName, err)
+	}
+	info := TagInfo{Name: tagName}
+	for _, ref := range refs {
+		if ref.Name == tagName {
+			info.Hash = ref.Hash
 		}
+	}
+	if info.Hash.IsZero() {
+		return TagInfo{}, fmt.Errorf("failed to find tag '%s': %w", tagName, plumbing.ErrReferenceNotFound)
+	}
+	return readTagInfo(b, info.Name, info.Hash)
+}
 
-		if !semver.FullPattern.MatchString(versionString) {
-			return nil
+// readTagInfo reads the tag object of the tag tagName whose ref points to hash.
+func readTagInfo(b Backend, tagName string, hash plumbing.Hash) (TagInfo, error) {
+	info := TagInfo{Name: tagName, Hash: hash}
+	var err error
+	info.Object, err = b.TagObject(hash)
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		return info, nil
+	}
+	if err != nil {
+		return TagInfo{}, fmt.Errorf("failed to read tag '%s': %w", tagName, err)
+	}
+	return info, nil
+}
+
+// TagMessage returns the message of an annotated tag, and false if the tag is lightweight.
+func TagMessage(repo *git.Repository, tagName string) (string, bool, error) {
+	info, err := FetchTagInfo(NewGoGitBackend(repo), tagName)
+	if err != nil || !info.Annotated() {
+		return "", false, err
+	}
+	return info.Object.Message, true, nil
+}
+
+// ComponentVersion describes the latest semantic version tag found for a single tag prefix.
+type ComponentVersion struct {
+	Tag     string
+	Version semver.SemVer
+	Commit  *object.Commit
+}
+
+// FetchComponentVersions scans the repository tags once with b and groups every semantic version tag
+// by its prefix, so that <prefix>/v<semver> tag families (including nested prefixes such as
+// services/api) can be listed in a single call. Tags without a prefix are grouped under the empty string.
+// For each prefix the tag with the highest version is returned along with the commit it points to.
+// Tag names are split into prefix and version according to the given TagFormat.
+func FetchComponentVersions(b Backend, format TagFormat) (map[string]ComponentVersion, error) {
+	tags, err := b.TagRefs("refs/tags/")
+	if err != nil {
+		return nil, err
+	}
+
+	latestRefs := make(map[string]TagRef)
+	latestVersions := make(map[string]semver.SemVer)
+	for _, ref := range tags {
+		prefix, candidateVersion, ok := format.Parse(ref.Name)
+		if !ok {
+			continue
+		}
+		if current, ok := latestVersions[prefix]; !ok || candidateVersion.Compare(current) > 0 {
+			latestRefs[prefix] = ref
+			latestVersions[prefix] = candidateVersion
 		}
+	}
 
-		candidateVersion, err := semver.Parse(versionString)
+	components := make(map[string]ComponentVersion, len(latestRefs))
+	for prefix, ref := range latestRefs {
+		commit, err := b.Commit(ref.Commit)
 		if err != nil {
-			return nil
+			return nil, fmt.Errorf("failed to resolve tag '%s': %w", ref.Name, err)
 		}
+		components[prefix] = ComponentVersion{
+			Tag:     ref.Name,
+			Version: latestVersions[prefix],
+			Commit:  commit,
+		}
+	}
+
+	return components, nil
+}
+
+// prefixTagRefs returns the tags of b that can be version tags for prefix, and the matcher of their
+// version.
+func prefixTagRefs(b Backend, format TagFormat, prefix string) ([]TagRef, *regexp.Regexp, error) {
+	matcher, err := format.matcher(prefix)
+	if err != nil {
+		return nil, nil, err
+	}
+	literal, err := format.literalPrefix(prefix)
+	if err != nil {
+		return nil, nil, err
+	}
+	tags, err := b.TagRefs(plumbing.NewTagReferenceName(literal).String())
+	if err != nil {
+		return nil, nil, err
+	}
+	return tags, matcher, nil
+}
 
-		refStr := ref.Name().String()
-		var candidateCommit *object.Commit
-		if cached, ok := commitCache[refStr]; ok {
-			candidateCommit = cached
-		} else {
-			candidateCommit, err = FetchCommitObject(repo, refStr)
-			if err != nil {
-				return nil
-			}
-			commitCache[refStr] = candidateCommit
+// ListVersionTags returns the version of every tag for prefix, keyed by tag name, looked up with b.
+func ListVersionTags(b Backend, format TagFormat, prefix string) (map[string]semver.SemVer, error) {
+	tags, matcher, err := prefixTagRefs(b, format, prefix)
+	if err != nil {
+		return nil, err
+	}
+	versions := make(map[string]semver.SemVer)
+	for _, ref := range tags {
+		if _, version, ok := matchLayout(matcher, ref.Name); ok {
+			versions[ref.Name] = version
 		}
+	}
+	return versions, nil
+}
 
-		if exactCommit {
-			if candidateCommit.Hash != targetCommit.Hash {
-				return nil
-			}
-			if foundCommit == nil || candidateVersion.Compare(foundVersion) > 0 {
-				foundCommit = candidateCommit
-				foundTag = candidateTagName
-				foundVersion = candidateVersion
-			}
-		} else {
-			if candidateCommit.Committer.When.After(targetTime) {
-				return nil
-			}
-			if foundCommit == nil || candidateCommit.Committer.When.After(foundCommit.Committer.When) {
-				foundCommit = candidateCommit
-				foundTag = candidateTagName
-				foundVersion = candidateVersion
-			} else if candidateCommit.Hash == foundCommit.Hash && candidateVersion.Compare(foundVersion) > 0 {
-				foundTag = candidateTagName
-				foundVersion = candidateVersion
-			}
+// NextPreReleaseCounter returns the counter for the next <label>.<N> prerelease of base for prefix: one more
+// than the highest N of the existing tags with the same major, minor and patch version, or 1 if there are none.
+func NextPreReleaseCounter(b Backend, format TagFormat, prefix string, base semver.SemVer, label semver.PreRelease) (int, error) {
+	versions, err := ListVersionTags(b, format, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	next := 1
+	for _, version := range versions {
+		if version.Release() != base.Release() {
+			continue
 		}
-		return nil
-	})
+		if n, ok := version.PreReleaseCounter(label); ok && n >= next {
+			next = n + 1
+		}
+	}
+	return next, nil
+}
 
+// CheckVersionTagAvailable verifies with b that a version tag can be created for prefix and version: no tag
+// with the same name may exist, and no other tag for the prefix may carry a version of equal precedence
+// (e.g. v1.2.3+build.1 collides with v1.2.3, as build metadata does not affect precedence).
+func CheckVersionTagAvailable(b Backend, format TagFormat, prefix string, version semver.SemVer) error {
+	tagName, err := format.Format(prefix, version)
 	if err != nil {
-		return "", semver.SemVer{}, nil, err
+		return err
+	}
+	if _, err := b.ResolveRevision(plumbing.NewTagReferenceName(tagName).String()); err == nil {
+		return WrapSentinel(ErrTagExists, "tag %s already exists", tagName)
 	}
 
-	return foundTag, foundVersion, foundCommit, nil
+	tags, matcher, err := prefixTagRefs(b, format, prefix)
+	if err != nil {
+		return err
+	}
+	for _, ref := range tags {
+		_, existingVersion, ok := matchLayout(matcher, ref.Name)
+		if ok && existingVersion.Compare(version) == 0 {
+			return WrapSentinel(ErrTagExists, "tag %s collides with existing tag %s of the same version precedence", tagName, ref.Name)
+		}
+	}
+	return nil
 }
 
-// CreateVersionTag creates a new Git tag for the given targetCommit with the specified semantic version.
-// It constructs the new tag name using an optional prefix. If annotated is true, the tag will include
-// a message and tagger information. It returns the new tag name or an error.
-func CreateVersionTag(repo *git.Repository, targetCommit *object.Commit, version semver.SemVer, prefix string, annotated bool) (string, error) {
-	newTagName := fmt.Sprintf("v%s", version.String())
-	if prefix != "" {
-		newTagName = prefix + "/" + newTagName
+// ErrTagExistsOnRemote is returned by PushTag if the remote already has the tag pointing elsewhere.
+// It is an ErrPushRejected.
+var ErrTagExistsOnRemote error = &sentinelError{msg: "tag already exists on the remote", err: ErrPushRejected}
+
+// PushTag pushes a tag to the named remote. Unlike a plain push, it never moves a tag on the remote:
+// if the remote has the tag pointing to another object, e.g. because a concurrent job created the
+// same version, ErrTagExistsOnRemote is returned. Pushing a tag the remote already has is a no-op;
+// other rejections of the push by the remote are returned as ErrPushRejected.
+func PushTag(repo *git.Repository, remoteName, tagName string, auth transport.AuthMethod) error {
+	return NewGoGitBackend(repo).PushTag(remoteName, tagName, auth)
+}
+
+// FetchTagsResult lists the tags created, moved and kept by FetchTags.
+type FetchTagsResult struct {
+	New     []string
+	Updated []string
+	// Conflicting are the local tags that differ from the remote and were kept.
+	Conflicting []string
+}
+
+// ListRemoteTags reports the tags FetchTags would create, move and keep, without fetching them or
+// changing any ref: the tags of the named remote that are missing locally (New), and those that point
+// to another object (Updated if overwrite is true, otherwise Conflicting).
+func ListRemoteTags(repo *git.Repository, remoteName string, auth transport.AuthMethod, overwrite bool) (FetchTagsResult, error) {
+	remote, err := repo.Remote(remoteName)
+	if err != nil {
+		return FetchTagsResult{}, fmt.Errorf("failed to find remote '%s': %w", remoteName, err)
+	}
+	refs, err := remote.List(&git.ListOptions{Auth: auth})
+	if err != nil {
+		return FetchTagsResult{}, fmt.Errorf("failed to list tags of remote %s: %w", remoteName, err)
 	}
 
-	var tagOpts *git.CreateTagOptions
-	if annotated {
-		tagOpts = &git.CreateTagOptions{
-			Message: fmt.Sprintf("Version %s", version.String()),
-			Tagger:  &targetCommit.Committer,
+	result := FetchTagsResult{New: []string{}, Updated: []string{}, Conflicting: []string{}}
+	for _, ref := range refs {
+		// Peeled annotated tags are listed as "<tag>^{}" as well.
+		if !ref.Name().IsTag() || strings.HasSuffix(ref.Name().String(), "^{}") {
+			continue
+		}
+		local, err := repo.Reference(ref.Name(), false)
+		switch {
+		case errors.Is(err, plumbing.ErrReferenceNotFound):
+			result.New = append(result.New, ref.Name().Short())
+		case err != nil:
+			return FetchTagsResult{}, fmt.Errorf("failed to retrieve tags: %w", err)
+		case local.Hash() != ref.Hash() && overwrite:
+			result.Updated = append(result.Updated, ref.Name().Short())
+		case local.Hash() != ref.Hash():
+			result.Conflicting = append(result.Conflicting, ref.Name().Short())
 		}
 	}
+	sort.Strings(result.New)
+	sort.Strings(result.Updated)
+	sort.Strings(result.Conflicting)
+	return result, nil
+}
 
-	if _, err := repo.CreateTag(newTagName, targetCommit.Hash, tagOpts); err != nil {
-		return "", fmt.Errorf("failed to create tag: %w", err)
+// FetchTags fetches the tags of the named remote that are missing locally. Local tags that differ
+// from the remote are kept and reported as conflicting, unless overwrite is true, in which case they
+// are replaced by the remote tags.
+func FetchTags(repo *git.Repository, remoteName string, auth transport.AuthMethod, overwrite bool) (FetchTagsResult, error) {
+	result, err := ListRemoteTags(repo, remoteName, auth, overwrite)
+	if err != nil {
+		return FetchTagsResult{}, err
+	}
+
+	var refSpecs []config.RefSpec
+	for _, name := range result.New {
+		ref := plumbing.NewTagReferenceName(name)
+		refSpecs = append(refSpecs, config.RefSpec(ref+":"+ref))
+	}
+	for _, name := range result.Updated {
+		ref := plumbing.NewTagReferenceName(name)
+		refSpecs = append(refSpecs, config.RefSpec("+"+ref+":"+ref))
+	}
+	if len(refSpecs) == 0 {
+		return result, nil
 	}
 
-	return newTagName, nil
+	err = repo.Fetch(&git.FetchOptions{
+		RemoteName: remoteName,
+		RefSpecs:   refSpecs,
+		Tags:       git.NoTags,
+		Auth:       auth,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return FetchTagsResult{}, fmt.Errorf("failed to fetch tags from remote %s: %w", remoteName, err)
+	}
+	return result, nil
+}
+
+// TagsFingerprint returns a hash over the names and targets of all tags in the repository, read with b,
+// which changes whenever a tag is created, deleted or moved.
+func TagsFingerprint(b Backend) (string, error) {
+	tags, err := b.TagRefs("refs/tags/")
+	if err != nil {
+		return "", err
+	}
+
+	lines := make([]string, 0, len(tags))
+	for _, ref := range tags {
+		lines = append(lines, plumbing.NewTagReferenceName(ref.Name).String()+" "+ref.Hash.String())
+	}
+	sort.Strings(lines)
+
+	h := sha256.New()
+	for _, line := range lines {
+		h.Write([]byte(line + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
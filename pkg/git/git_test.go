@@ -1,6 +1,8 @@
 package git
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
@@ -11,6 +13,7 @@ import (
 	"github.com/coreeng/semver-utils/pkg/semver"
 	"github.com/go-git/go-billy/v5/memfs"
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/storage/memory"
@@ -32,6 +35,59 @@ func setupRepo() (*git.Repository, []*object.Commit, error) {
 	if err != nil {
 		return nil, nil, err
 	}
+	return populateRepo(repo)
+}
+
+// setupRepoOnDisk creates the repository of setupRepo in dir, so that the git binary can read it.
+func setupRepoOnDisk(dir string) (*git.Repository, []*object.Commit, error) {
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	return populateRepo(repo)
+}
+
+// forEachBackend runs test with every Backend: go-git on the repository of setupRepo, and the git
+// binary on the same repository on disk.
+func forEachBackend(t *testing.T, test func(t *testing.T, repo *git.Repository, commits []*object.Commit, b Backend)) {
+	t.Run(BackendGoGit, func(t *testing.T) {
+		repo, commits, err := setupRepo()
+		require.NoError(t, err)
+		test(t, repo, commits, NewGoGitBackend(repo))
+	})
+	t.Run(BackendGit, func(t *testing.T) {
+		repo, commits, err := setupRepoOnDisk(t.TempDir())
+		require.NoError(t, err)
+		b, err := NewBackend(repo, BackendGit)
+		require.NoError(t, err)
+		defer func() { assert.NoError(t, b.Close()) }()
+		test(t, repo, commits, b)
+	})
+}
+
+// fetchVersionTagWith looks up a version tag with b through FetchVersionTagContext, returning an empty
+// tag name instead of ErrNoVersionTag like FetchVersionTag.
+func fetchVersionTagWith(b Backend, targetCommit *object.Commit, format TagFormat, prefix string, exactCommit bool) (string, semver.SemVer, *object.Commit, error) {
+	tag, err := FetchVersionTagContext(context.Background(), nil, FetchOptions{
+		Revision: targetCommit.Hash.String(), Prefix: prefix, Format: format, ExactCommit: exactCommit, Backend: b,
+	})
+	if errors.Is(err, ErrNoVersionTag) {
+		return "", semver.SemVer{}, nil, nil
+	}
+	return tag.Name, tag.Version, tag.Commit, err
+}
+
+// createVersionTagWith creates a version tag with b through CreateVersionTagContext: a lightweight tag
+// if annotation is nil, else an annotated tag.
+func createVersionTagWith(b Backend, targetCommit *object.Commit, version semver.SemVer, format TagFormat, prefix string, annotation *TagOptions) (string, error) {
+	tag, err := CreateVersionTagContext(context.Background(), nil, CreateOptions{
+		Revision: targetCommit.Hash.String(), Prefix: prefix, Format: format, Version: version, Annotation: annotation, Backend: b,
+	})
+	return tag.Name, err
+}
+
+// populateRepo creates the commits, tags and branches of setupRepo in repo.
+func populateRepo(repo *git.Repository) (*git.Repository, []*object.Commit, error) {
 	w, err := repo.Worktree()
 	if err != nil {
 		return nil, nil, err
@@ -153,165 +209,177 @@ func setupRepo() (*git.Repository, []*object.Commit, error) {
 }
 
 func TestFetchCommitObject(t *testing.T) {
-	repo, commits, err := setupRepo()
-	assert.NoError(t, err)
-	assert.NotNil(t, repo)
-	assert.Len(t, commits, 5)
-
-	// HEAD should be on the "Fifth commit" (index 4)
-	headRef, err := repo.Head()
-	assert.NoError(t, err)
-	assert.Equal(t, commits[4].Hash, headRef.Hash())
+	forEachBackend(t, func(t *testing.T, repo *git.Repository, commits []*object.Commit, b Backend) {
+		assert.Len(t, commits, 5)
 
-	t.Run("Fetch HEAD", func(t *testing.T) {
-		commit, err := FetchCommitObject(repo, "HEAD")
+		// HEAD should be on the "Fifth commit" (index 4)
+		headRef, err := repo.Head()
 		assert.NoError(t, err)
-		assert.NotNil(t, commit)
-		assert.Equal(t, commits[4].Hash, commit.Hash)
-	})
+		assert.Equal(t, commits[4].Hash, headRef.Hash())
 
-	t.Run("Fetch commit by hash", func(t *testing.T) {
-		headHash := commits[4].Hash.String()
-		commit, err := FetchCommitObject(repo, headHash)
-		assert.NoError(t, err)
-		assert.NotNil(t, commit)
-		assert.Equal(t, commits[4].Hash, commit.Hash)
-	})
+		t.Run("Fetch HEAD", func(t *testing.T) {
+			commit, err := fetchCommitObject(b, "HEAD")
+			assert.NoError(t, err)
+			assert.NotNil(t, commit)
+			assert.Equal(t, commits[4].Hash, commit.Hash)
+		})
 
-	t.Run("Fetch commit by branch name", func(t *testing.T) {
-		commit, err := FetchCommitObject(repo, "test-branch")
-		assert.NoError(t, err)
-		assert.NotNil(t, commit)
-		assert.Equal(t, commits[1].Hash, commit.Hash)
-	})
+		t.Run("Fetch commit by hash", func(t *testing.T) {
+			headHash := commits[4].Hash.String()
+			commit, err := fetchCommitObject(b, headHash)
+			assert.NoError(t, err)
+			assert.NotNil(t, commit)
+			assert.Equal(t, commits[4].Hash, commit.Hash)
+		})
 
-	t.Run("Fetch commit by lightweight tag (v1.0.0)", func(t *testing.T) {
-		commit, err := FetchCommitObject(repo, "v1.0.0")
-		assert.NoError(t, err)
-		assert.NotNil(t, commit)
-		assert.Equal(t, commits[2].Hash, commit.Hash)
-	})
+		t.Run("Fetch commit by branch name", func(t *testing.T) {
+			commit, err := fetchCommitObject(b, "test-branch")
+			assert.NoError(t, err)
+			assert.NotNil(t, commit)
+			assert.Equal(t, commits[1].Hash, commit.Hash)
+		})
 
-	t.Run("Fetch commit by annotated tag", func(t *testing.T) {
-		commit, err := FetchCommitObject(repo, "annotated-tag")
-		assert.NoError(t, err)
-		assert.NotNil(t, commit)
-		assert.Equal(t, commits[2].Hash, commit.Hash)
-	})
+		t.Run("Fetch commit by lightweight tag (v1.0.0)", func(t *testing.T) {
+			commit, err := fetchCommitObject(b, "v1.0.0")
+			assert.NoError(t, err)
+			assert.NotNil(t, commit)
+			assert.Equal(t, commits[2].Hash, commit.Hash)
+		})
+
+		t.Run("Fetch commit by annotated tag", func(t *testing.T) {
+			commit, err := fetchCommitObject(b, "annotated-tag")
+			assert.NoError(t, err)
+			assert.NotNil(t, commit)
+			assert.Equal(t, commits[2].Hash, commit.Hash)
+		})
 
-	t.Run("Error fetching non-existent commit", func(t *testing.T) {
-		commit, err := FetchCommitObject(repo, "non-existent-ref")
-		assert.Error(t, err)
-		assert.Nil(t, commit)
+		t.Run("Error fetching non-existent commit", func(t *testing.T) {
+			commit, err := fetchCommitObject(b, "non-existent-ref")
+			assert.Error(t, err)
+			assert.Nil(t, commit)
+		})
 	})
 }
 
-func TestFetchVersionTagExact(t *testing.T) {
-	repo, _, err := setupRepo()
-	assert.NoError(t, err)
+func TestCurrentBranch(t *testing.T) {
+	repo, commits, err := setupRepo()
+	require.NoError(t, err)
 
-	head, err := repo.Head()
-	assert.NoError(t, err)
+	branch, err := CurrentBranch(repo)
+	require.NoError(t, err)
+	assert.Equal(t, "master", branch)
 
-	headCommit, err := repo.CommitObject(head.Hash())
-	assert.NoError(t, err)
+	// Detach HEAD onto the second commit.
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference(plumbing.HEAD, commits[1].Hash)))
+	branch, err = CurrentBranch(repo)
+	require.NoError(t, err)
+	assert.Empty(t, branch)
+}
 
-	t.Run("Find tag without prefix", func(t *testing.T) {
-		tag, ver, commitObj, err := FetchVersionTag(repo, headCommit, "", true)
-		assert.NoError(t, err)
-		assert.Equal(t, "v1.4.0-alpha.1", tag)
-		expectedVer, err := semver.Parse("1.4.0-alpha.1")
+func TestFetchVersionTagExact(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo *git.Repository, commits []*object.Commit, b Backend) {
+		head, err := repo.Head()
 		assert.NoError(t, err)
-		assert.True(t, ver.Compare(expectedVer) == 0)
-		assert.Equal(t, headCommit.Hash, commitObj.Hash)
-	})
 
-	t.Run("Find tag with prefix", func(t *testing.T) {
-		tag, ver, commitObj, err := FetchVersionTag(repo, headCommit, "prefixed", true)
+		headCommit, err := repo.CommitObject(head.Hash())
 		assert.NoError(t, err)
-		assert.Equal(t, "prefixed/v1.0.0", tag)
-		expectedVer, err := semver.Parse("1.0.0")
-		assert.NoError(t, err)
-		assert.True(t, ver.Compare(expectedVer) == 0)
-		assert.Equal(t, headCommit.Hash, commitObj.Hash)
-	})
 
-	t.Run("No matching tag", func(t *testing.T) {
-		tag, _, _, err := FetchVersionTag(repo, headCommit, "non-existent-prefix", true)
-		assert.NoError(t, err)
-		assert.Empty(t, tag)
-	})
+		t.Run("Find tag without prefix", func(t *testing.T) {
+			tag, ver, commitObj, err := fetchVersionTagWith(b, headCommit, DefaultTagFormat, "", true)
+			assert.NoError(t, err)
+			assert.Equal(t, "v1.4.0-alpha.1", tag)
+			expectedVer, err := semver.Parse("1.4.0-alpha.1")
+			assert.NoError(t, err)
+			assert.True(t, ver.Compare(expectedVer) == 0)
+			assert.Equal(t, headCommit.Hash, commitObj.Hash)
+		})
 
-	t.Run("No matching tag with prefix", func(t *testing.T) {
-		tag, _, _, err := FetchVersionTag(repo, headCommit, "some", true)
-		assert.NoError(t, err)
-		assert.Empty(t, tag)
-	})
+		t.Run("Find tag with prefix", func(t *testing.T) {
+			tag, ver, commitObj, err := fetchVersionTagWith(b, headCommit, DefaultTagFormat, "prefixed", true)
+			assert.NoError(t, err)
+			assert.Equal(t, "prefixed/v1.0.0", tag)
+			expectedVer, err := semver.Parse("1.0.0")
+			assert.NoError(t, err)
+			assert.True(t, ver.Compare(expectedVer) == 0)
+			assert.Equal(t, headCommit.Hash, commitObj.Hash)
+		})
 
-	t.Run("Tag on different commit", func(t *testing.T) {
-		commitsIter, err := repo.Log(&git.LogOptions{})
-		assert.NoError(t, err)
-		_, err = commitsIter.Next() // Skip HEAD
-		assert.NoError(t, err)
-		secondCommit, err := commitsIter.Next()
-		assert.NoError(t, err)
+		t.Run("No matching tag", func(t *testing.T) {
+			tag, _, _, err := fetchVersionTagWith(b, headCommit, DefaultTagFormat, "non-existent-prefix", true)
+			assert.NoError(t, err)
+			assert.Empty(t, tag)
+		})
 
-		tag, _, _, err := FetchVersionTag(repo, secondCommit, "", true)
-		assert.NoError(t, err)
-		assert.Empty(t, tag)
+		t.Run("No matching tag with prefix", func(t *testing.T) {
+			tag, _, _, err := fetchVersionTagWith(b, headCommit, DefaultTagFormat, "some", true)
+			assert.NoError(t, err)
+			assert.Empty(t, tag)
+		})
+
+		t.Run("Tag on different commit", func(t *testing.T) {
+			commitsIter, err := repo.Log(&git.LogOptions{})
+			assert.NoError(t, err)
+			_, err = commitsIter.Next() // Skip HEAD
+			assert.NoError(t, err)
+			secondCommit, err := commitsIter.Next()
+			assert.NoError(t, err)
+
+			tag, _, _, err := fetchVersionTagWith(b, secondCommit, DefaultTagFormat, "", true)
+			assert.NoError(t, err)
+			assert.Empty(t, tag)
+		})
 	})
 }
 
 func TestFetchVersionTag(t *testing.T) {
-	repo, commits, err := setupRepo()
-	assert.NoError(t, err)
-	assert.NotNil(t, repo)
-	assert.Len(t, commits, 5)
-
-	t.Run("Find previous version tag without prefix", func(t *testing.T) {
-		tag, ver, commitObj, err := FetchVersionTag(repo, commits[4], "", false)
-		assert.NoError(t, err)
-		assert.Equal(t, "v1.4.0-alpha.1", tag)
-
-		expectedVer, err := semver.Parse("1.4.0-alpha.1")
-		assert.NoError(t, err)
-		assert.True(t, ver.Compare(expectedVer) == 0)
-		assert.Equal(t, commits[4].Hash, commitObj.Hash)
-	})
+	forEachBackend(t, func(t *testing.T, repo *git.Repository, commits []*object.Commit, b Backend) {
+		assert.Len(t, commits, 5)
+
+		t.Run("Find previous version tag without prefix", func(t *testing.T) {
+			tag, ver, commitObj, err := fetchVersionTagWith(b, commits[4], DefaultTagFormat, "", false)
+			assert.NoError(t, err)
+			assert.Equal(t, "v1.4.0-alpha.1", tag)
+
+			expectedVer, err := semver.Parse("1.4.0-alpha.1")
+			assert.NoError(t, err)
+			assert.True(t, ver.Compare(expectedVer) == 0)
+			assert.Equal(t, commits[4].Hash, commitObj.Hash)
+		})
 
-	t.Run("Find previous version tag with prefix", func(t *testing.T) {
-		tag, ver, commitObj, err := FetchVersionTag(repo, commits[4], "release", false)
-		assert.NoError(t, err)
-		assert.Equal(t, "release/v1.0.0", tag)
-		expectedVer, err := semver.Parse("1.0.0")
-		assert.NoError(t, err)
-		assert.True(t, ver.Compare(expectedVer) == 0)
-		assert.Equal(t, commits[3].Hash, commitObj.Hash)
-	})
+		t.Run("Find previous version tag with prefix", func(t *testing.T) {
+			tag, ver, commitObj, err := fetchVersionTagWith(b, commits[4], DefaultTagFormat, "release", false)
+			assert.NoError(t, err)
+			assert.Equal(t, "release/v1.0.0", tag)
+			expectedVer, err := semver.Parse("1.0.0")
+			assert.NoError(t, err)
+			assert.True(t, ver.Compare(expectedVer) == 0)
+			assert.Equal(t, commits[3].Hash, commitObj.Hash)
+		})
 
-	t.Run("No previous version tag", func(t *testing.T) {
-		tag, ver, commitObj, err := FetchVersionTag(repo, commits[0], "", false)
-		assert.NoError(t, err)
-		assert.Empty(t, tag)
-		assert.Equal(t, semver.SemVer{}, ver)
-		assert.Nil(t, commitObj)
-	})
+		t.Run("No previous version tag", func(t *testing.T) {
+			tag, ver, commitObj, err := fetchVersionTagWith(b, commits[0], DefaultTagFormat, "", false)
+			assert.NoError(t, err)
+			assert.Empty(t, tag)
+			assert.Equal(t, semver.SemVer{}, ver)
+			assert.Nil(t, commitObj)
+		})
 
-	t.Run("Ignore non-semver tags", func(t *testing.T) {
-		tag, _, commitObj, err := FetchVersionTag(repo, commits[4], "", false)
-		assert.NoError(t, err)
-		assert.NotEqual(t, "non-semver-tag", tag)
-		assert.NotEqual(t, "pre/non-semver", tag)
-		assert.Equal(t, "v1.4.0-alpha.1", tag)
-		assert.Equal(t, commits[4].Hash, commitObj.Hash)
-	})
+		t.Run("Ignore non-semver tags", func(t *testing.T) {
+			tag, _, commitObj, err := fetchVersionTagWith(b, commits[4], DefaultTagFormat, "", false)
+			assert.NoError(t, err)
+			assert.NotEqual(t, "non-semver-tag", tag)
+			assert.NotEqual(t, "pre/non-semver", tag)
+			assert.Equal(t, "v1.4.0-alpha.1", tag)
+			assert.Equal(t, commits[4].Hash, commitObj.Hash)
+		})
 
-	t.Run("Tag on second commit, no previous version", func(t *testing.T) {
-		tag, ver, commitObj, err := FetchVersionTag(repo, commits[1], "", false)
-		assert.NoError(t, err)
-		assert.Empty(t, tag)
-		assert.Equal(t, semver.SemVer{}, ver)
-		assert.Nil(t, commitObj)
+		t.Run("Tag on second commit, no previous version", func(t *testing.T) {
+			tag, ver, commitObj, err := fetchVersionTagWith(b, commits[1], DefaultTagFormat, "", false)
+			assert.NoError(t, err)
+			assert.Empty(t, tag)
+			assert.Equal(t, semver.SemVer{}, ver)
+			assert.Nil(t, commitObj)
+		})
 	})
 }
 
@@ -456,77 +524,457 @@ func TestCreateIncrementedVersionTag(t *testing.T) {
 	for _, tc := range tests {
 		tc := tc // capture range variable
 		t.Run(tc.name, func(t *testing.T) {
-			repo, commits, err := setupRepo()
-			require.NoError(t, err)
-			require.Len(t, commits, 5)
-			targetCommit := commits[4]
+			forEachBackend(t, func(t *testing.T, repo *git.Repository, commits []*object.Commit, b Backend) {
+				require.Len(t, commits, 5)
+				targetCommit := commits[4]
 
-			// Use FetchVersionTag instead of the removed FindPreviousVersionTag.
-			prevTag, prevVersion, _, err := FetchVersionTag(repo, targetCommit, tc.prefix, false)
-			require.NoError(t, err)
-			if prevTag == "" {
-				if tc.expectErr && strings.Contains(tc.errContains, "no valid previous version tag found") {
-					// Expected error: no valid previous version tag found.
-					return
+				// Use FetchVersionTag instead of the removed FindPreviousVersionTag.
+				prevTag, prevVersion, _, err := fetchVersionTagWith(b, targetCommit, DefaultTagFormat, tc.prefix, false)
+				require.NoError(t, err)
+				if prevTag == "" {
+					if tc.expectErr && strings.Contains(tc.errContains, "no valid previous version tag found") {
+						// Expected error: no valid previous version tag found.
+						return
+					}
+					t.Fatalf("no valid previous version tag found")
 				}
-				t.Fatalf("no valid previous version tag found")
-			}
 
-			// Bump the version based on the incrementType.
-			var newVersion semver.SemVer
-			var bumpErr error
-			switch strings.ToLower(tc.incrementType) {
-			case "major":
-				newVersion = prevVersion.BumpMajor()
-			case "minor":
-				newVersion = prevVersion.BumpMinor()
-			case "patch":
-				newVersion = prevVersion.BumpPatch()
-			default:
-				bumpErr = fmt.Errorf("unknown increment type: %s", tc.incrementType)
-			}
-			if bumpErr != nil {
+				// Bump the version based on the incrementType.
+				var newVersion semver.SemVer
+				var bumpErr error
+				switch strings.ToLower(tc.incrementType) {
+				case "major":
+					newVersion = prevVersion.BumpMajor()
+				case "minor":
+					newVersion = prevVersion.BumpMinor()
+				case "patch":
+					newVersion = prevVersion.BumpPatch()
+				default:
+					bumpErr = fmt.Errorf("unknown increment type: %s", tc.incrementType)
+				}
+				if bumpErr != nil {
+					if tc.expectErr {
+						require.Error(t, bumpErr)
+						require.Contains(t, bumpErr.Error(), tc.errContains)
+						return
+					}
+					require.NoError(t, bumpErr)
+				}
+
+				// Apply prerelease and build metadata modifications if provided.
+				if tc.prerelease != "" {
+					newVersion, err = newVersion.SetPreRelease(semver.PreRelease(tc.prerelease))
+					require.NoError(t, err)
+				}
+				if tc.buildMetadata != "" {
+					newVersion, err = newVersion.SetBuildMetadata(semver.BuildMetadata(tc.buildMetadata))
+					require.NoError(t, err)
+				}
+
+				var tagOpts *TagOptions
+				if tc.annotated {
+					tagOpts = &TagOptions{}
+				}
+				newTagName, err := createVersionTagWith(b, targetCommit, newVersion, DefaultTagFormat, tc.prefix, tagOpts)
 				if tc.expectErr {
-					require.Error(t, bumpErr)
-					require.Contains(t, bumpErr.Error(), tc.errContains)
+					require.Error(t, err)
+					require.Contains(t, err.Error(), tc.errContains)
 					return
 				}
-				require.NoError(t, bumpErr)
-			}
-
-			// Apply prerelease and build metadata modifications if provided.
-			if tc.prerelease != "" {
-				newVersion, err = newVersion.SetPreRelease(semver.PreRelease(tc.prerelease))
 				require.NoError(t, err)
-			}
-			if tc.buildMetadata != "" {
-				newVersion, err = newVersion.SetBuildMetadata(semver.BuildMetadata(tc.buildMetadata))
+				require.Equal(t, tc.expectedTagName, newTagName)
+				require.Equal(t, tc.expectedVersion, newVersion.String())
+
+				// Verify that the new tag exists in the repository.
+				ref, err := repo.Reference(plumbing.NewTagReferenceName(newTagName), true)
 				require.NoError(t, err)
-			}
+				if tc.annotated {
+					tagObj, err := repo.TagObject(ref.Hash())
+					require.NoError(t, err)
+					actualMsg := strings.TrimSpace(tagObj.Message)
+					expectedMsg := fmt.Sprintf("Version %s", newVersion.String())
+					require.Equal(t, expectedMsg, actualMsg)
+				} else {
+					_, err = repo.TagObject(ref.Hash())
+					require.Error(t, err)
+				}
+			})
+		})
+	}
+}
 
-			newTagName, err := CreateVersionTag(repo, targetCommit, newVersion, tc.prefix, tc.annotated)
-			if tc.expectErr {
-				require.Error(t, err)
-				require.Contains(t, err.Error(), tc.errContains)
-				return
-			}
-			require.NoError(t, err)
-			require.Equal(t, tc.expectedTagName, newTagName)
-			require.Equal(t, tc.expectedVersion, newVersion.String())
+func TestFetchComponentVersions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo *git.Repository, commits []*object.Commit, b Backend) {
+		require.Len(t, commits, 5)
+
+		// Add a nested prefix and an older release to check grouping and ordering.
+		_, err := repo.CreateTag("services/api/v2.1.0", commits[1].Hash, nil)
+		require.NoError(t, err)
+		_, err = repo.CreateTag("services/api/v2.0.0", commits[3].Hash, nil)
+		require.NoError(t, err)
+
+		components, err := FetchComponentVersions(b, DefaultTagFormat)
+		require.NoError(t, err)
+		require.Len(t, components, 4)
+
+		expected := map[string]struct {
+			tag     string
+			version string
+			commit  plumbing.Hash
+		}{
+			"":             {"v1.4.0-alpha.1", "1.4.0-alpha.1", commits[4].Hash},
+			"release":      {"release/v1.0.0", "1.0.0", commits[3].Hash},
+			"prefixed":     {"prefixed/v1.0.0", "1.0.0", commits[4].Hash},
+			"services/api": {"services/api/v2.1.0", "2.1.0", commits[1].Hash},
+		}
+		for prefix, exp := range expected {
+			component, ok := components[prefix]
+			require.True(t, ok, "missing component %q", prefix)
+			assert.Equal(t, exp.tag, component.Tag)
+			assert.Equal(t, exp.version, component.Version.String())
+			assert.Equal(t, exp.commit, component.Commit.Hash)
+		}
+
+		_, ok := components["pre"]
+		assert.False(t, ok, "non-semver tags must not produce a component")
+	})
+}
 
-			// Verify that the new tag exists in the repository.
-			ref, err := repo.Reference(plumbing.NewTagReferenceName(newTagName), true)
+func TestVersionTagWithFormat(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo *git.Repository, commits []*object.Commit, b Backend) {
+		format, err := ParseTagFormat("{prefix}-{version}")
+		require.NoError(t, err)
+
+		_, err = repo.CreateTag("api-1.0.0", commits[2].Hash, nil)
+		require.NoError(t, err)
+
+		tag, version, commit, err := fetchVersionTagWith(b, commits[4], format, "api", false)
+		require.NoError(t, err)
+		assert.Equal(t, "api-1.0.0", tag)
+		assert.Equal(t, "1.0.0", version.String())
+		assert.Equal(t, commits[2].Hash, commit.Hash)
+
+		newTag, err := createVersionTagWith(b, commits[4], version.BumpMinor(), format, "api", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "api-1.1.0", newTag)
+
+		tag, _, commit, err = fetchVersionTagWith(b, commits[4], format, "api", true)
+		require.NoError(t, err)
+		assert.Equal(t, "api-1.1.0", tag)
+		assert.Equal(t, commits[4].Hash, commit.Hash)
+
+		// Tags in the default layout are not matched by a custom format.
+		tag, _, _, err = fetchVersionTagWith(b, commits[4], format, "release", false)
+		require.NoError(t, err)
+		assert.Empty(t, tag)
+	})
+}
+
+func TestCheckVersionTagAvailable(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo *git.Repository, commits []*object.Commit, b Backend) {
+		_, err := repo.CreateTag("release/v1.1.0+build.7", commits[4].Hash, nil)
+		require.NoError(t, err)
+
+		mustParse := func(v string) semver.SemVer {
+			version, err := semver.Parse(v)
 			require.NoError(t, err)
-			if tc.annotated {
-				tagObj, err := repo.TagObject(ref.Hash())
-				require.NoError(t, err)
-				actualMsg := strings.TrimSpace(tagObj.Message)
-				expectedMsg := fmt.Sprintf("Version %s", newVersion.String())
-				require.Equal(t, expectedMsg, actualMsg)
-			} else {
-				_, err = repo.TagObject(ref.Hash())
-				require.Error(t, err)
-			}
+			return version
+		}
+
+		t.Run("Available version", func(t *testing.T) {
+			assert.NoError(t, CheckVersionTagAvailable(b, DefaultTagFormat, "release", mustParse("1.0.1")))
+		})
+
+		t.Run("Existing tag name", func(t *testing.T) {
+			err := CheckVersionTagAvailable(b, DefaultTagFormat, "release", mustParse("1.0.0"))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "tag release/v1.0.0 already exists")
+			assert.ErrorIs(t, err, ErrTagExists)
+		})
+
+		t.Run("Same precedence with different build metadata", func(t *testing.T) {
+			err := CheckVersionTagAvailable(b, DefaultTagFormat, "release", mustParse("1.1.0"))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "collides with existing tag release/v1.1.0+build.7")
+			assert.ErrorIs(t, err, ErrTagExists)
+		})
+
+		t.Run("Same version under another prefix", func(t *testing.T) {
+			assert.NoError(t, CheckVersionTagAvailable(b, DefaultTagFormat, "other", mustParse("1.0.0")))
 		})
+	})
+}
+
+func TestTagsFingerprint(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo *git.Repository, commits []*object.Commit, b Backend) {
+		before, err := TagsFingerprint(b)
+		require.NoError(t, err)
+		again, err := TagsFingerprint(b)
+		require.NoError(t, err)
+		assert.Equal(t, before, again)
+
+		_, err = repo.CreateTag("v9.9.9", commits[0].Hash, nil)
+		require.NoError(t, err)
+		after, err := TagsFingerprint(b)
+		require.NoError(t, err)
+		assert.NotEqual(t, before, after)
+	})
+
+	// A plan made with one backend can be applied with the other.
+	repo, _, err := setupRepoOnDisk(t.TempDir())
+	require.NoError(t, err)
+	b, err := NewBackend(repo, BackendGit)
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, b.Close()) }()
+	goGit, err := TagsFingerprint(NewGoGitBackend(repo))
+	require.NoError(t, err)
+	viaGit, err := TagsFingerprint(b)
+	require.NoError(t, err)
+	assert.Equal(t, goGit, viaGit)
+}
+
+func TestBranchFromEnv(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(name string) string { return vars[name] }
 	}
+
+	assert.Empty(t, BranchFromEnv(env(nil)))
+	assert.Equal(t, "feature/login", BranchFromEnv(env(map[string]string{
+		"GITHUB_HEAD_REF": "feature/login",
+		"GITHUB_REF_NAME": "42/merge",
+		"GITHUB_REF_TYPE": "branch",
+	})))
+	assert.Equal(t, "main", BranchFromEnv(env(map[string]string{
+		"GITHUB_REF_NAME": "main",
+		"GITHUB_REF_TYPE": "branch",
+	})))
+	assert.Empty(t, BranchFromEnv(env(map[string]string{
+		"GITHUB_REF_NAME": "v1.0.0",
+		"GITHUB_REF_TYPE": "tag",
+	})))
+	assert.Equal(t, "release/1.x", BranchFromEnv(env(map[string]string{"CI_COMMIT_BRANCH": "release/1.x"})))
+	assert.Equal(t, "main", BranchFromEnv(env(map[string]string{"GIT_BRANCH": "origin/main"})))
+}
+
+func TestCommitsSince(t *testing.T) {
+	_, commits, err := setupRepo()
+	require.NoError(t, err)
+
+	count, err := CommitsSince(commits[1], commits[4])
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	count, err = CommitsSince(commits[4], commits[4])
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	count, err = CommitsSince(nil, commits[2])
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
+
+func TestCommitLog(t *testing.T) {
+	_, commits, err := setupRepo()
+	require.NoError(t, err)
+
+	log, err := CommitLog(commits[2], commits[4])
+	require.NoError(t, err)
+	require.Len(t, log, 2)
+	assert.Equal(t, commits[4].Hash, log[0].Hash)
+	assert.Equal(t, commits[3].Hash, log[1].Hash)
+}
+
+func TestNextPreReleaseCounter(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo *git.Repository, commits []*object.Commit, b Backend) {
+		base := semver.SemVer{Major: 1, Minor: 1, Patch: 0}
+
+		next, err := NextPreReleaseCounter(b, DefaultTagFormat, "release", base, "rc")
+		require.NoError(t, err)
+		assert.Equal(t, 1, next)
+
+		for _, tag := range []string{"release/v1.1.0-rc.1", "release/v1.1.0-rc.2", "release/v1.1.0-beta.7", "release/v1.2.0-rc.9", "other/v1.1.0-rc.5"} {
+			_, err = repo.CreateTag(tag, commits[4].Hash, nil)
+			require.NoError(t, err)
+		}
+
+		next, err = NextPreReleaseCounter(b, DefaultTagFormat, "release", base, "rc")
+		require.NoError(t, err)
+		assert.Equal(t, 3, next)
+
+		next, err = NextPreReleaseCounter(b, DefaultTagFormat, "release", base, "beta")
+		require.NoError(t, err)
+		assert.Equal(t, 8, next)
+
+		versions, err := ListVersionTags(b, DefaultTagFormat, "other")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]semver.SemVer{"other/v1.1.0-rc.5": {Major: 1, Minor: 1, Patch: 0, PreRelease: "rc.5"}}, versions)
+	})
+}
+
+func TestTagMessage(t *testing.T) {
+	repo, commits, err := setupRepo()
+	require.NoError(t, err)
+
+	tag, err := createVersionTagWith(NewGoGitBackend(repo), commits[4], semver.SemVer{Major: 2}, DefaultTagFormat, "release", &TagOptions{Message: "Release notes"})
+	require.NoError(t, err)
+	assert.Equal(t, "release/v2.0.0", tag)
+
+	message, annotated, err := TagMessage(repo, tag)
+	require.NoError(t, err)
+	assert.True(t, annotated)
+	assert.Equal(t, "Release notes\n", message)
+
+	_, err = repo.CreateTag("lightweight/v1.0.0", commits[4].Hash, nil)
+	require.NoError(t, err)
+	message, annotated, err = TagMessage(repo, "lightweight/v1.0.0")
+	require.NoError(t, err)
+	assert.False(t, annotated)
+	assert.Empty(t, message)
+
+	_, _, err = TagMessage(repo, "missing/v1.0.0")
+	assert.Error(t, err)
+}
+
+func TestFetchTagInfo(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo *git.Repository, commits []*object.Commit, b Backend) {
+		tagger := &object.Signature{Name: "Release Bot", Email: "release@example.com", When: time.Unix(1700000000, 0)}
+		tag, err := createVersionTagWith(b, commits[4], semver.SemVer{Major: 2}, DefaultTagFormat, "release", &TagOptions{Message: "Release notes", Tagger: tagger})
+		require.NoError(t, err)
+
+		info, err := FetchTagInfo(b, tag)
+		require.NoError(t, err)
+		assert.Equal(t, "release/v2.0.0", info.Name)
+		assert.True(t, info.Annotated())
+		assert.NotEqual(t, commits[4].Hash, info.Hash)
+		assert.Equal(t, "Release Bot", info.Object.Tagger.Name)
+		assert.Equal(t, int64(1700000000), info.Object.Tagger.When.Unix())
+		assert.Equal(t, "Release notes\n", info.Object.Message)
+
+		_, err = createVersionTagWith(b, commits[4], semver.SemVer{Major: 1}, DefaultTagFormat, "lightweight", nil)
+		require.NoError(t, err)
+		info, err = FetchTagInfo(b, "lightweight/v1.0.0")
+		require.NoError(t, err)
+		assert.False(t, info.Annotated())
+		assert.Equal(t, commits[4].Hash, info.Hash)
+
+		_, err = FetchTagInfo(b, "missing/v1.0.0")
+		assert.ErrorIs(t, err, plumbing.ErrReferenceNotFound)
+	})
+}
+
+func TestFetchTags(t *testing.T) {
+	remoteDir := t.TempDir()
+	_, err := git.PlainInit(remoteDir, true)
+	require.NoError(t, err)
+
+	source, _, err := setupRepo()
+	require.NoError(t, err)
+	_, err = source.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{remoteDir}})
+	require.NoError(t, err)
+	require.NoError(t, source.Push(&git.PushOptions{RemoteName: "origin", RefSpecs: []config.RefSpec{"refs/tags/*:refs/tags/*"}}))
+
+	// setupRepo is deterministic, so the local repository has the same commits as the remote.
+	local, commits, err := setupRepo()
+	require.NoError(t, err)
+	_, err = local.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{"file://" + remoteDir}})
+	require.NoError(t, err)
+	require.NoError(t, local.DeleteTag("v1.0.0"))
+	require.NoError(t, local.DeleteTag("first"))
+	_, err = local.CreateTag("first", commits[1].Hash, nil)
+	require.NoError(t, err)
+
+	// Listing the remote tags reports the tags without changing them.
+	result, err := ListRemoteTags(local, "origin", nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1.0.0"}, result.New)
+	assert.Equal(t, []string{"first"}, result.Conflicting)
+	_, err = local.Tag("v1.0.0")
+	assert.ErrorIs(t, err, git.ErrTagNotFound)
+
+	// Local tags that differ from the remote are kept.
+	result, err = FetchTags(local, "origin", nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1.0.0"}, result.New)
+	assert.Empty(t, result.Updated)
+	assert.Equal(t, []string{"first"}, result.Conflicting)
+	ref, err := local.Tag("first")
+	require.NoError(t, err)
+	assert.Equal(t, commits[1].Hash, ref.Hash())
+
+	result, err = FetchTags(local, "origin", nil, true)
+	require.NoError(t, err)
+	assert.Empty(t, result.New)
+	assert.Equal(t, []string{"first"}, result.Updated)
+	assert.Empty(t, result.Conflicting)
+	ref, err = local.Tag("first")
+	require.NoError(t, err)
+	assert.Equal(t, commits[0].Hash, ref.Hash())
+
+	result, err = FetchTags(local, "origin", nil, false)
+	require.NoError(t, err)
+	assert.Empty(t, result.New)
+	assert.Empty(t, result.Updated)
+	assert.Empty(t, result.Conflicting)
+}
+
+func TestPushTag(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo *git.Repository, commits []*object.Commit, b Backend) {
+		remoteDir := t.TempDir()
+		_, err := git.PlainInit(remoteDir, true)
+		require.NoError(t, err)
+
+		_, err = repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{remoteDir}})
+		require.NoError(t, err)
+
+		require.NoError(t, b.PushTag("origin", "v1.0.0", nil))
+		// Pushing a tag the remote already has is a no-op.
+		require.NoError(t, b.PushTag("origin", "v1.0.0", nil))
+
+		// A tag of the same name on another commit is never moved on the remote.
+		require.NoError(t, repo.DeleteTag("v1.0.0"))
+		_, err = repo.CreateTag("v1.0.0", commits[4].Hash, nil)
+		require.NoError(t, err)
+		err = b.PushTag("origin", "v1.0.0", nil)
+		assert.ErrorIs(t, err, ErrTagExistsOnRemote)
+		assert.ErrorIs(t, err, ErrPushRejected)
+
+		remote, err := git.PlainOpen(remoteDir)
+		require.NoError(t, err)
+		ref, err := remote.Tag("v1.0.0")
+		require.NoError(t, err)
+		assert.Equal(t, commits[2].Hash, ref.Hash())
+	})
+}
+
+func TestFetchVersionTagSameCommit(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo *git.Repository, commits []*object.Commit, b Backend) {
+		// The fifth commit is tagged v1.4.0-alpha.1; more tags on it prefer the highest version,
+		// whatever order the tags are read in.
+		for _, name := range []string{"v1.3.0", "v1.4.0", "v1.2.0"} {
+			_, err := repo.CreateTag(name, commits[4].Hash, nil)
+			require.NoError(t, err)
+		}
+		tag, version, commit, err := fetchVersionTagWith(b, commits[4], DefaultTagFormat, "", false)
+		require.NoError(t, err)
+		assert.Equal(t, "v1.4.0", tag)
+		assert.Equal(t, "1.4.0", version.String())
+		assert.Equal(t, commits[4].Hash, commit.Hash)
+
+		// A later commit with a lower version tag is still the most recent tag.
+		w, err := repo.Worktree()
+		require.NoError(t, err)
+		hash, err := w.Commit("Sixth commit", &git.CommitOptions{
+			AllowEmptyCommits: true,
+			Author:            &object.Signature{Name: "test", Email: "test@example.com", When: time.Unix(600, 0)},
+		})
+		require.NoError(t, err)
+		sixth, err := repo.CommitObject(hash)
+		require.NoError(t, err)
+		_, err = repo.CreateTag("v1.3.1", hash, nil)
+		require.NoError(t, err)
+		tag, _, commit, err = fetchVersionTagWith(b, sixth, DefaultTagFormat, "", false)
+		require.NoError(t, err)
+		assert.Equal(t, "v1.3.1", tag)
+		assert.Equal(t, sixth.Hash, commit.Hash)
+	})
 }
@@ -0,0 +1,38 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// OpenRepository opens the repository at path like git does: path may be the working tree of a
+// repository or any directory or file inside it, a linked worktree created by git worktree add, a
+// submodule, or a bare repository. The objects and refs that linked worktrees share with the main
+// repository are read from its common git directory.
+func OpenRepository(path string) (*git.Repository, error) {
+	// A bare repository has no .git to detect, so path itself is tried first.
+	repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if !errors.Is(err, git.ErrRepositoryNotExists) {
+		return repo, err
+	}
+	return git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true, EnableDotGitCommonDir: true})
+}
+
+// RepositoryRoot returns the top-level directory of the working tree of a repository opened from disk,
+// or the git directory of a bare repository.
+func RepositoryRoot(repo *git.Repository) (string, error) {
+	worktree, err := repo.Worktree()
+	if err == nil {
+		return worktree.Filesystem.Root(), nil
+	}
+	if !errors.Is(err, git.ErrIsBareRepository) {
+		return "", fmt.Errorf("failed to open worktree: %w", err)
+	}
+	fs, ok := repoFilesystem(repo)
+	if !ok {
+		return "", errors.New("repository is not on disk")
+	}
+	return fs.Root(), nil
+}
@@ -0,0 +1,89 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenRepository(t *testing.T) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git is not installed")
+	}
+	root := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command(gitPath, args...)
+		cmd.Env = append(cmd.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	main := filepath.Join(root, "main")
+	lib := filepath.Join(root, "lib")
+	run("init", "-q", lib)
+	run("-C", lib, "commit", "-q", "--allow-empty", "-m", "lib")
+	run("-C", lib, "tag", "lib/v2.0.0")
+	run("init", "-q", main)
+	require.NoError(t, os.MkdirAll(filepath.Join(main, "src", "cmd"), 0o755))
+	run("-C", main, "commit", "-q", "--allow-empty", "-m", "main")
+	run("-C", main, "tag", "v1.0.0")
+	run("-C", main, "-c", "protocol.file.allow=always", "submodule", "add", "-q", lib, "modules/lib")
+	run("-C", main, "commit", "-q", "-m", "add lib")
+	run("-C", main, "worktree", "add", "-q", "--detach", filepath.Join(root, "worktree"), "v1.0.0")
+	run("clone", "-q", "--bare", main, filepath.Join(root, "bare.git"))
+
+	tests := []struct {
+		name   string
+		path   string
+		root   string
+		prefix string
+		tag    string
+	}{
+		{name: "Working tree", path: main, root: main, tag: "v1.0.0"},
+		{name: "Subdirectory", path: filepath.Join(main, "src", "cmd"), root: main, tag: "v1.0.0"},
+		{name: "Linked worktree", path: filepath.Join(root, "worktree"), root: filepath.Join(root, "worktree"), tag: "v1.0.0"},
+		{name: "Submodule", path: filepath.Join(main, "modules", "lib"), root: filepath.Join(main, "modules", "lib"), prefix: "lib", tag: "lib/v2.0.0"},
+		{name: "Bare repository", path: filepath.Join(root, "bare.git"), root: filepath.Join(root, "bare.git"), tag: "v1.0.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, err := OpenRepository(tt.path)
+			require.NoError(t, err)
+			repoRoot, err := RepositoryRoot(repo)
+			require.NoError(t, err)
+			assert.Equal(t, tt.root, repoRoot)
+
+			commit, err := FetchCommitObject(repo, "HEAD")
+			require.NoError(t, err)
+			tag, _, _, err := fetchVersionTagWith(NewGoGitBackend(repo), commit, DefaultTagFormat, tt.prefix, false)
+			require.NoError(t, err)
+			assert.Equal(t, tt.tag, tag)
+		})
+	}
+
+	t.Run("Tags created in a linked worktree are shared", func(t *testing.T) {
+		repo, err := OpenRepository(filepath.Join(root, "worktree"))
+		require.NoError(t, err)
+		head, err := repo.Head()
+		require.NoError(t, err)
+		_, err = repo.CreateTag("v1.0.1", head.Hash(), nil)
+		require.NoError(t, err)
+
+		mainRepo, err := git.PlainOpen(main)
+		require.NoError(t, err)
+		_, err = mainRepo.Reference(plumbing.NewTagReferenceName("v1.0.1"), false)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Not a repository", func(t *testing.T) {
+		_, err := OpenRepository(t.TempDir())
+		assert.ErrorIs(t, err, git.ErrRepositoryNotExists)
+	})
+}
@@ -0,0 +1,102 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// ErrShallowRepository is returned when a version tag cannot be found because the repository is a
+// shallow clone whose history may not contain it.
+var ErrShallowRepository = errors.New("repository is a shallow clone")
+
+// IsShallow reports whether the repository is a shallow clone, i.e. has a .git/shallow file.
+func IsShallow(repo *git.Repository) (bool, error) {
+	shallow, err := repo.Storer.Shallow()
+	if err != nil {
+		return false, fmt.Errorf("failed to read shallow commits: %w", err)
+	}
+	return len(shallow) > 0, nil
+}
+
+// Deepen fetches depth more commits of history, and the tags pointing into it, from remoteName into a
+// shallow clone. A depth of 0 fetches the complete history. go-git cannot deepen a shallow clone, so
+// the git binary is used; auth is passed to it for HTTP basic auth and tokens, otherwise git uses its
+// own credentials. The repository is reopened to see the fetched objects.
+func Deepen(repo *git.Repository, remoteName string, depth int, auth transport.AuthMethod) (*git.Repository, error) {
+	storage, ok := repo.Storer.(*filesystem.Storage)
+	if !ok {
+		return nil, errors.New("deepening requires a repository on disk")
+	}
+	gitDir := storage.Filesystem().Root()
+
+	args := []string{"fetch", "--quiet", "--tags"}
+	if depth > 0 {
+		args = append(args, "--deepen="+strconv.Itoa(depth))
+	} else {
+		args = append(args, "--unshallow")
+	}
+	args = append(args, remoteName)
+	if _, err := runGit(remoteCommand(gitDir, remoteName, auth, args...), nil); err != nil {
+		return nil, fmt.Errorf("failed to deepen history from remote %s: %w", remoteName, err)
+	}
+
+	root, err := RepositoryRoot(repo)
+	if err != nil {
+		return nil, err
+	}
+	reopened, err := OpenRepository(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reopen repository: %w", err)
+	}
+	return reopened, nil
+}
+
+// DeepenUntil deepens a shallow clone from remoteName until found reports true or the history is
+// complete, starting with depth commits and doubling the depth each time. A depth of 0 fetches the
+// complete history at once. It returns the reopened repository, or repo if it is not shallow or
+// found is already true.
+func DeepenUntil(repo *git.Repository, remoteName string, depth int, auth transport.AuthMethod, found func(*git.Repository) (bool, error)) (*git.Repository, error) {
+	var previous []plumbing.Hash
+	for {
+		shallow, err := repo.Storer.Shallow()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read shallow commits: %w", err)
+		}
+		// Stop when the history is complete, or deepening made no progress.
+		if len(shallow) == 0 || (previous != nil && sameHashes(shallow, previous)) {
+			return repo, nil
+		}
+		ok, err := found(repo)
+		if err != nil || ok {
+			return repo, err
+		}
+		previous = shallow
+		repo, err = Deepen(repo, remoteName, depth, auth)
+		if err != nil {
+			return nil, err
+		}
+		depth *= 2
+	}
+}
+
+func sameHashes(a, b []plumbing.Hash) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[plumbing.Hash]bool, len(a))
+	for _, h := range a {
+		seen[h] = true
+	}
+	for _, h := range b {
+		if !seen[h] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package git
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupShallowClone creates a repository of ten commits with the tag v1.0.0 on the third, and returns
+// a shallow clone of depth 1 of it.
+func setupShallowClone(t *testing.T) *git.Repository {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git is not installed")
+	}
+	root := t.TempDir()
+	upstream := filepath.Join(root, "upstream")
+	run := func(args ...string) {
+		cmd := exec.Command(gitPath, args...)
+		cmd.Env = append(cmd.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	run("init", "-q", upstream)
+	for i := 0; i < 10; i++ {
+		run("-C", upstream, "commit", "-q", "--allow-empty", "-m", "commit")
+		if i == 2 {
+			run("-C", upstream, "tag", "v1.0.0")
+		}
+	}
+	clone := filepath.Join(root, "clone")
+	run("clone", "-q", "--depth", "1", "file://"+upstream, clone)
+
+	repo, err := git.PlainOpen(clone)
+	require.NoError(t, err)
+	return repo
+}
+
+func TestIsShallow(t *testing.T) {
+	repo, _, err := setupRepo()
+	require.NoError(t, err)
+	shallow, err := IsShallow(repo)
+	require.NoError(t, err)
+	assert.False(t, shallow)
+
+	repo = setupShallowClone(t)
+	shallow, err = IsShallow(repo)
+	require.NoError(t, err)
+	assert.True(t, shallow)
+
+	repo, err = Deepen(repo, "origin", 0, nil)
+	require.NoError(t, err)
+	shallow, err = IsShallow(repo)
+	require.NoError(t, err)
+	assert.False(t, shallow)
+	_, err = repo.Tag("v1.0.0")
+	assert.NoError(t, err)
+}
+
+func TestDeepenUntil(t *testing.T) {
+	repo := setupShallowClone(t)
+	head, err := repo.Head()
+	require.NoError(t, err)
+
+	calls := 0
+	repo, err = DeepenUntil(repo, "origin", 2, nil, func(repo *git.Repository) (bool, error) {
+		calls++
+		commit, err := repo.CommitObject(head.Hash())
+		if err != nil {
+			return false, err
+		}
+		tag, _, tagCommit, err := FetchVersionTag(repo, commit, "", false)
+		if err != nil || tag == "" {
+			return false, err
+		}
+		reachable, err := tagCommit.IsAncestor(commit)
+		return err == nil && reachable, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	// The history is deepened by 2 and then 4 commits, which reaches the tag 7 commits below HEAD.
+	shallow, err := IsShallow(repo)
+	require.NoError(t, err)
+	assert.True(t, shallow)
+	commit, err := repo.CommitObject(head.Hash())
+	require.NoError(t, err)
+	tag, _, _, err := FetchVersionTag(repo, commit, "", false)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.0", tag)
+}
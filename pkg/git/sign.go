@@ -0,0 +1,244 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Errors returned by VerifyTagSignature.
+var (
+	ErrTagNotSigned        = errors.New("tag is not signed")
+	ErrTagSignatureInvalid = errors.New("tag signature does not verify with the trusted keys")
+)
+
+// Environment variables holding the tag signing key and its passphrase, which are never read from
+// flags or configuration files.
+const (
+	EnvSigningKeyArmored    = "SEMVER_GIT_SIGNING_KEY_ARMORED"
+	EnvSigningKeyPassphrase = "SEMVER_GIT_SIGNING_KEY_PASSPHRASE"
+)
+
+// SigningKeyOptions configures the OpenPGP private key used to sign tags.
+type SigningKeyOptions struct {
+	// Path is a file holding an armored or binary private key, or a keyring of several keys
+	// (e.g. exported with `gpg --export-secret-keys`).
+	Path string
+	// Armored is an armored private key, used if Path is empty.
+	Armored string
+	// ID selects the key of a keyring by key ID, fingerprint or a part of its user ID (e.g. the email).
+	ID string
+	// Passphrase decrypts the private key if it is protected.
+	Passphrase string
+}
+
+// SigningSecretsFromEnv fills the armored key and the passphrase of opts from the SEMVER_GIT_*
+// environment variables.
+func SigningSecretsFromEnv(opts SigningKeyOptions, getenv func(string) string) SigningKeyOptions {
+	opts.Armored = getenv(EnvSigningKeyArmored)
+	opts.Passphrase = getenv(EnvSigningKeyPassphrase)
+	return opts
+}
+
+// Secrets returns the secret values set in opts, for redaction: the passphrase and the armored key.
+func (opts SigningKeyOptions) Secrets() []string {
+	var secrets []string
+	for _, s := range []string{opts.Passphrase, opts.Armored} {
+		if s != "" {
+			secrets = append(secrets, s)
+		}
+	}
+	return secrets
+}
+
+// LoadSigningKey reads the private key configured by opts and decrypts it with the passphrase,
+// ready to be used as git.CreateTagOptions.SignKey.
+func LoadSigningKey(opts SigningKeyOptions) (*openpgp.Entity, error) {
+	var data []byte
+	source := opts.Path
+	switch {
+	case opts.Path != "":
+		var err error
+		data, err = os.ReadFile(opts.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signing key: %w", err)
+		}
+	case opts.Armored != "":
+		data = []byte(opts.Armored)
+		source = EnvSigningKeyArmored
+	default:
+		return nil, fmt.Errorf("no signing key: set a key file or %s", EnvSigningKeyArmored)
+	}
+
+	keyring, err := readKeyRing(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key from %s: %w", source, err)
+	}
+
+	var candidates []*openpgp.Entity
+	for _, entity := range keyring {
+		if entity.PrivateKey != nil && (opts.ID == "" || matchesKeyID(entity, opts.ID)) {
+			candidates = append(candidates, entity)
+		}
+	}
+	switch {
+	case len(candidates) == 0 && opts.ID != "":
+		return nil, fmt.Errorf("no private key matching '%s' in %s", opts.ID, source)
+	case len(candidates) == 0:
+		return nil, fmt.Errorf("no private key in %s", source)
+	case len(candidates) > 1:
+		return nil, fmt.Errorf("%s holds %d private keys: select one by key ID", source, len(candidates))
+	}
+
+	entity := candidates[0]
+	if err := decryptEntity(entity, opts.Passphrase); err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
+// readKeyRing reads binary keys, or one or more concatenated armored key blocks.
+func readKeyRing(data []byte) (openpgp.EntityList, error) {
+	const armorHeader = "-----BEGIN PGP"
+	if !bytes.Contains(data, []byte(armorHeader)) {
+		return openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	var keyring openpgp.EntityList
+	blocks := strings.Split(string(data), armorHeader)
+	for _, block := range blocks[1:] {
+		entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armorHeader + block))
+		if err != nil {
+			return nil, err
+		}
+		keyring = append(keyring, entities...)
+	}
+	return keyring, nil
+}
+
+// matchesKeyID reports whether id is the (short or long) key ID or fingerprint of the entity or one of
+// its subkeys, or a part of one of its user IDs.
+func matchesKeyID(entity *openpgp.Entity, id string) bool {
+	hexID := strings.ToUpper(strings.TrimPrefix(strings.ReplaceAll(id, " ", ""), "0x"))
+	matchesKey := func(keyID string, fingerprint []byte) bool {
+		return (len(hexID) >= 8 && strings.HasSuffix(keyID, hexID)) || fmt.Sprintf("%X", fingerprint) == hexID
+	}
+	if matchesKey(entity.PrimaryKey.KeyIdString(), entity.PrimaryKey.Fingerprint) {
+		return true
+	}
+	for _, subkey := range entity.Subkeys {
+		if matchesKey(subkey.PublicKey.KeyIdString(), subkey.PublicKey.Fingerprint) {
+			return true
+		}
+	}
+	for name := range entity.Identities {
+		if strings.Contains(name, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// decryptEntity decrypts the private keys of the entity that are protected by a passphrase.
+func decryptEntity(entity *openpgp.Entity, passphrase string) error {
+	encrypted := entity.PrivateKey.Encrypted
+	for _, subkey := range entity.Subkeys {
+		encrypted = encrypted || (subkey.PrivateKey != nil && subkey.PrivateKey.Encrypted)
+	}
+	if !encrypted {
+		return nil
+	}
+	if passphrase == "" {
+		return fmt.Errorf("signing key is protected by a passphrase: set %s", EnvSigningKeyPassphrase)
+	}
+	if err := entity.DecryptPrivateKeys([]byte(passphrase)); err != nil {
+		return errors.New("failed to decrypt signing key: wrong passphrase")
+	}
+	return nil
+}
+
+// LoadTrustedKeys reads the public keys tag signatures are verified with from an armored or binary
+// keyring file (e.g. exported with `gpg --armor --export`).
+func LoadTrustedKeys(path string) (openpgp.EntityList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trusted keys: %w", err)
+	}
+	keyring, err := readKeyRing(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trusted keys from %s: %w", path, err)
+	}
+	if len(keyring) == 0 {
+		return nil, fmt.Errorf("no keys in %s", path)
+	}
+	return keyring, nil
+}
+
+// VerifyTagSignature checks that the tag tagName is an annotated tag signed by one of the trusted keys,
+// and returns the key that signed it. It returns ErrTagNotSigned for lightweight and unsigned tags and
+// ErrTagSignatureInvalid if the signature does not verify.
+func VerifyTagSignature(repo *git.Repository, tagName string, trustedKeys openpgp.EntityList) (*openpgp.Entity, error) {
+	return verifyTagSignature(NewGoGitBackend(repo), tagName, trustedKeys)
+}
+
+func verifyTagSignature(b Backend, tagName string, trustedKeys openpgp.EntityList) (*openpgp.Entity, error) {
+	info, err := FetchTagInfo(b, tagName)
+	if err != nil {
+		return nil, err
+	}
+	return verifyTagInfo(info, trustedKeys)
+}
+
+// verifyTagInfo verifies the signature of a tag that was already looked up.
+func verifyTagInfo(info TagInfo, trustedKeys openpgp.EntityList) (*openpgp.Entity, error) {
+	tagName := info.Name
+	if !info.Annotated() {
+		return nil, fmt.Errorf("%w: %s is a lightweight tag", ErrTagNotSigned, tagName)
+	}
+	tagObject := info.Object
+	if tagObject.PGPSignature == "" {
+		return nil, fmt.Errorf("%w: %s", ErrTagNotSigned, tagName)
+	}
+
+	encoded := &plumbing.MemoryObject{}
+	if err := tagObject.EncodeWithoutSignature(encoded); err != nil {
+		return nil, fmt.Errorf("failed to encode tag '%s': %w", tagName, err)
+	}
+	reader, err := encoded.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode tag '%s': %w", tagName, err)
+	}
+	signer, err := openpgp.CheckArmoredDetachedSignature(trustedKeys, reader, strings.NewReader(tagObject.PGPSignature), nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrTagSignatureInvalid, tagName, err)
+	}
+	return signer, nil
+}
+
+// SignerIdentity returns the primary user ID of a key, e.g. "Release Bot <release@example.com>".
+func SignerIdentity(key *openpgp.Entity) string {
+	if identity := key.PrimaryIdentity(); identity != nil {
+		return identity.Name
+	}
+	return ""
+}
+
+// SignerFingerprint returns the fingerprint of a key in upper-case hex.
+func SignerFingerprint(key *openpgp.Entity) string {
+	return fmt.Sprintf("%X", key.PrimaryKey.Fingerprint)
+}
+
+// SignatureVerification configures how FetchVersionTagContext verifies version tag signatures, see
+// FetchOptions.Verify.
+type SignatureVerification struct {
+	// TrustedKeys are the public keys version tags must be signed with.
+	TrustedKeys openpgp.EntityList
+	// FailOnUnverified makes FetchVersionTagContext fail if the version tag it finds is not signed by a
+	// trusted key. Otherwise such tags are skipped, as if they did not exist.
+	FailOnUnverified bool
+}
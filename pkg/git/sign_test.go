@@ -0,0 +1,196 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/coreeng/semver-utils/pkg/semver"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSigningKey generates an OpenPGP key, protected by passphrase if it is not empty, and returns
+// its armored private and public keys.
+func newSigningKey(t *testing.T, name, email, passphrase string) (string, string) {
+	entity, err := openpgp.NewEntity(name, "", email, nil)
+	require.NoError(t, err)
+	if passphrase != "" {
+		require.NoError(t, entity.EncryptPrivateKeys([]byte(passphrase), nil))
+	}
+
+	var private, public bytes.Buffer
+	w, err := armor.Encode(&private, openpgp.PrivateKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.SerializePrivateWithoutSigning(w, nil))
+	require.NoError(t, w.Close())
+	w, err = armor.Encode(&public, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(w))
+	require.NoError(t, w.Close())
+	return private.String(), public.String()
+}
+
+func TestLoadSigningKey(t *testing.T) {
+	private, _ := newSigningKey(t, "Release Bot", "release@example.com", "secret")
+	keyFile := filepath.Join(t.TempDir(), "key.asc")
+	require.NoError(t, os.WriteFile(keyFile, []byte(private), 0600))
+
+	key, err := LoadSigningKey(SigningKeyOptions{Path: keyFile, Passphrase: "secret"})
+	require.NoError(t, err)
+	assert.False(t, key.PrivateKey.Encrypted)
+
+	key, err = LoadSigningKey(SigningKeyOptions{Armored: private, Passphrase: "secret"})
+	require.NoError(t, err)
+	assert.False(t, key.PrivateKey.Encrypted)
+
+	_, err = LoadSigningKey(SigningKeyOptions{Path: keyFile})
+	assert.ErrorContains(t, err, EnvSigningKeyPassphrase)
+	_, err = LoadSigningKey(SigningKeyOptions{Path: keyFile, Passphrase: "wrong"})
+	assert.ErrorContains(t, err, "wrong passphrase")
+	_, err = LoadSigningKey(SigningKeyOptions{})
+	assert.Error(t, err)
+	_, err = LoadSigningKey(SigningKeyOptions{Path: filepath.Join(t.TempDir(), "missing.asc")})
+	assert.Error(t, err)
+}
+
+func TestSigningKeyOptionsSecrets(t *testing.T) {
+	private, _ := newSigningKey(t, "Release Bot", "release@example.com", "secret")
+	opts := SigningSecretsFromEnv(SigningKeyOptions{}, func(name string) string {
+		return map[string]string{EnvSigningKeyArmored: private, EnvSigningKeyPassphrase: "secret"}[name]
+	})
+	assert.ElementsMatch(t, []string{"secret", private}, opts.Secrets())
+
+	// The armored key spans many lines and is redacted as a whole.
+	require.Contains(t, private, "\n")
+	msg := RedactSecrets("failed to read key "+private+" with secret", opts.Secrets()...)
+	assert.Equal(t, "failed to read key *** with ***", msg)
+}
+
+func TestLoadSigningKeyFromKeyring(t *testing.T) {
+	first, _ := newSigningKey(t, "First", "first@example.com", "")
+	second, _ := newSigningKey(t, "Second", "second@example.com", "")
+	keyring := filepath.Join(t.TempDir(), "keyring.asc")
+	require.NoError(t, os.WriteFile(keyring, []byte(first+second), 0600))
+
+	_, err := LoadSigningKey(SigningKeyOptions{Path: keyring})
+	assert.ErrorContains(t, err, "2 private keys")
+
+	key, err := LoadSigningKey(SigningKeyOptions{Path: keyring, ID: "second@example.com"})
+	require.NoError(t, err)
+	assert.Contains(t, key.PrimaryIdentity().Name, "second@example.com")
+
+	key, err = LoadSigningKey(SigningKeyOptions{Path: keyring, ID: key.PrimaryKey.KeyIdString()})
+	require.NoError(t, err)
+	assert.Contains(t, key.PrimaryIdentity().Name, "second@example.com")
+
+	_, err = LoadSigningKey(SigningKeyOptions{Path: keyring, ID: "third@example.com"})
+	assert.ErrorContains(t, err, "no private key matching")
+}
+
+func TestCreateVersionTagSigned(t *testing.T) {
+	repo, commits, err := setupRepo()
+	require.NoError(t, err)
+	private, public := newSigningKey(t, "Release Bot", "release@example.com", "secret")
+	key, err := LoadSigningKey(SigningKeyOptions{Armored: private, Passphrase: "secret"})
+	require.NoError(t, err)
+
+	tag, err := createVersionTagWith(NewGoGitBackend(repo), commits[4], semver.SemVer{Major: 2}, DefaultTagFormat, "release", &TagOptions{SignKey: key})
+	require.NoError(t, err)
+	assert.Equal(t, "release/v2.0.0", tag)
+
+	ref, err := repo.Tag(tag)
+	require.NoError(t, err)
+	tagObject, err := repo.TagObject(ref.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, "Version 2.0.0\n", tagObject.Message)
+	assert.NotEmpty(t, tagObject.PGPSignature)
+	signer, err := tagObject.Verify(public)
+	require.NoError(t, err)
+	assert.Contains(t, signer.PrimaryIdentity().Name, "release@example.com")
+}
+
+func TestVerifyTagSignature(t *testing.T) {
+	repo, commits, err := setupRepo()
+	require.NoError(t, err)
+	trustedPrivate, trustedPublic := newSigningKey(t, "Release Bot", "release@example.com", "")
+	otherPrivate, _ := newSigningKey(t, "Someone Else", "else@example.com", "")
+	trustedKey, err := LoadSigningKey(SigningKeyOptions{Armored: trustedPrivate})
+	require.NoError(t, err)
+	otherKey, err := LoadSigningKey(SigningKeyOptions{Armored: otherPrivate})
+	require.NoError(t, err)
+	trustedFile := filepath.Join(t.TempDir(), "trusted.asc")
+	require.NoError(t, os.WriteFile(trustedFile, []byte(trustedPublic), 0600))
+	trustedKeys, err := LoadTrustedKeys(trustedFile)
+	require.NoError(t, err)
+
+	_, err = createVersionTagWith(NewGoGitBackend(repo), commits[1], semver.SemVer{Major: 1}, DefaultTagFormat, "signed", &TagOptions{SignKey: trustedKey})
+	require.NoError(t, err)
+	_, err = createVersionTagWith(NewGoGitBackend(repo), commits[3], semver.SemVer{Major: 1, Minor: 1}, DefaultTagFormat, "signed", &TagOptions{SignKey: otherKey})
+	require.NoError(t, err)
+	_, err = repo.CreateTag("signed/v1.2.0", commits[4].Hash, nil)
+	require.NoError(t, err)
+
+	signer, err := VerifyTagSignature(repo, "signed/v1.0.0", trustedKeys)
+	require.NoError(t, err)
+	assert.Equal(t, "Release Bot <release@example.com>", SignerIdentity(signer))
+	assert.Equal(t, SignerFingerprint(trustedKey), SignerFingerprint(signer))
+
+	_, err = VerifyTagSignature(repo, "signed/v1.1.0", trustedKeys)
+	assert.ErrorIs(t, err, ErrTagSignatureInvalid)
+	_, err = VerifyTagSignature(repo, "signed/v1.2.0", trustedKeys)
+	assert.ErrorIs(t, err, ErrTagNotSigned)
+	_, err = VerifyTagSignature(repo, "annotated-tag", trustedKeys)
+	assert.ErrorIs(t, err, ErrTagNotSigned)
+
+	// Unverified tags are skipped by default.
+	fetch := func(commit *object.Commit, exactCommit bool, verify SignatureVerification) (VersionTag, error) {
+		return FetchVersionTagContext(context.Background(), repo.Storer, FetchOptions{
+			Revision: commit.Hash.String(), Prefix: "signed", ExactCommit: exactCommit, Verify: &verify,
+		})
+	}
+	tag, err := fetch(commits[4], false, SignatureVerification{TrustedKeys: trustedKeys})
+	require.NoError(t, err)
+	assert.Equal(t, "signed/v1.0.0", tag.Name)
+	assert.Equal(t, "1.0.0", tag.Version.String())
+	assert.Equal(t, commits[1].Hash, tag.Commit.Hash)
+	assert.Equal(t, "Release Bot <release@example.com>", SignerIdentity(tag.Signer))
+
+	_, err = fetch(commits[4], true, SignatureVerification{TrustedKeys: trustedKeys})
+	assert.ErrorIs(t, err, ErrNoVersionTag)
+
+	// With FailOnUnverified, the latest tag must verify.
+	_, err = fetch(commits[4], false, SignatureVerification{TrustedKeys: trustedKeys, FailOnUnverified: true})
+	assert.ErrorIs(t, err, ErrTagNotSigned)
+	_, err = fetch(commits[3], false, SignatureVerification{TrustedKeys: trustedKeys, FailOnUnverified: true})
+	assert.ErrorIs(t, err, ErrTagSignatureInvalid)
+	tag, err = fetch(commits[2], false, SignatureVerification{TrustedKeys: trustedKeys, FailOnUnverified: true})
+	require.NoError(t, err)
+	assert.Equal(t, "signed/v1.0.0", tag.Name)
+	assert.NotNil(t, tag.Signer)
+
+	// Skipping unverified tags lists the tags once instead of once per candidate.
+	counting := &countingBackend{Backend: NewGoGitBackend(repo)}
+	tag, err = FetchVersionTagContext(context.Background(), nil, FetchOptions{
+		Revision: commits[4].Hash.String(), Prefix: "signed", Verify: &SignatureVerification{TrustedKeys: trustedKeys}, Backend: counting,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "signed/v1.0.0", tag.Name)
+	assert.Equal(t, 1, counting.tagRefs)
+}
+
+// countingBackend counts the calls to TagRefs.
+type countingBackend struct {
+	Backend
+	tagRefs int
+}
+
+func (b *countingBackend) TagRefs(refPrefix string) ([]TagRef, error) {
+	b.tagRefs++
+	return b.Backend.TagRefs(refPrefix)
+}
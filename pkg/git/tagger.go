@@ -0,0 +1,114 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// EnvSourceDateEpoch is the reproducible builds variable holding the Unix time to record instead of now.
+const EnvSourceDateEpoch = "SOURCE_DATE_EPOCH"
+
+// ConfigIdentity returns the name and email git records as the tagger: GIT_COMMITTER_NAME and
+// GIT_COMMITTER_EMAIL if set, else committer.* or user.* from the repository, global or system
+// configuration. Either may be empty if it is not configured.
+func ConfigIdentity(repo *git.Repository, getenv func(string) string) (string, string, error) {
+	cfg, err := repo.ConfigScoped(config.SystemScope)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read git config: %w", err)
+	}
+	name := firstNonEmpty(getenv("GIT_COMMITTER_NAME"), cfg.Committer.Name, cfg.User.Name)
+	email := firstNonEmpty(getenv("GIT_COMMITTER_EMAIL"), cfg.Committer.Email, cfg.User.Email)
+	return name, email, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// ParseTagDate parses a tagger date given as Unix seconds or in RFC 3339 format.
+func ParseTagDate(s string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid tagger date '%s': must be Unix seconds or RFC 3339", s)
+	}
+	return t, nil
+}
+
+// TagDate returns the tagger date: value if it is not empty (see ParseTagDate), else the time in
+// SOURCE_DATE_EPOCH if it is set, else now.
+func TagDate(value string, getenv func(string) string, now time.Time) (time.Time, error) {
+	if value != "" {
+		return ParseTagDate(value)
+	}
+	if epoch := getenv(EnvSourceDateEpoch); epoch != "" {
+		seconds, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid %s '%s': must be Unix seconds", EnvSourceDateEpoch, epoch)
+		}
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	return now, nil
+}
+
+// TagMessageData is the data a tag message template is executed with.
+type TagMessageData struct {
+	Tag             string
+	Version         string
+	Prefix          string
+	PreviousTag     string
+	PreviousVersion string
+	// Commits are the commits since the previous tag, newest first.
+	Commits []TagMessageCommit
+}
+
+// TagMessageCommit is a commit listed in TagMessageData.
+type TagMessageCommit struct {
+	Hash      string
+	ShortHash string
+	Subject   string
+	Author    string
+}
+
+// NewTagMessageCommits converts commits for TagMessageData.
+func NewTagMessageCommits(commits []*object.Commit) []TagMessageCommit {
+	result := make([]TagMessageCommit, 0, len(commits))
+	for _, c := range commits {
+		subject, _, _ := strings.Cut(strings.TrimSpace(c.Message), "\n")
+		result = append(result, TagMessageCommit{
+			Hash:      c.Hash.String(),
+			ShortHash: c.Hash.String()[:7],
+			Subject:   subject,
+			Author:    c.Author.Name,
+		})
+	}
+	return result
+}
+
+// RenderTagMessage executes a text/template tag message template with data.
+func RenderTagMessage(text string, data TagMessageData) (string, error) {
+	tmpl, err := template.New("message").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid message template: %w", err)
+	}
+	var message bytes.Buffer
+	if err := tmpl.Execute(&message, data); err != nil {
+		return "", fmt.Errorf("failed to render message template: %w", err)
+	}
+	return message.String(), nil
+}
@@ -0,0 +1,108 @@
+package git
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coreeng/semver-utils/pkg/semver"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigIdentity(t *testing.T) {
+	repo, _, err := setupRepo()
+	require.NoError(t, err)
+	cfg, err := repo.Config()
+	require.NoError(t, err)
+	cfg.User.Name = "Config User"
+	cfg.User.Email = "config@example.com"
+	require.NoError(t, repo.SetConfig(cfg))
+
+	name, email, err := ConfigIdentity(repo, func(string) string { return "" })
+	require.NoError(t, err)
+	assert.Equal(t, "Config User", name)
+	assert.Equal(t, "config@example.com", email)
+
+	env := map[string]string{"GIT_COMMITTER_NAME": "Env User", "GIT_COMMITTER_EMAIL": "env@example.com"}
+	name, email, err = ConfigIdentity(repo, func(key string) string { return env[key] })
+	require.NoError(t, err)
+	assert.Equal(t, "Env User", name)
+	assert.Equal(t, "env@example.com", email)
+}
+
+func TestTagDate(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	noEnv := func(string) string { return "" }
+	epochEnv := func(key string) string {
+		if key == EnvSourceDateEpoch {
+			return "1700000000"
+		}
+		return ""
+	}
+
+	date, err := TagDate("", noEnv, now)
+	require.NoError(t, err)
+	assert.Equal(t, now, date)
+
+	date, err = TagDate("", epochEnv, now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(1700000000, 0).UTC(), date)
+
+	date, err = TagDate("2024-01-02T03:04:05+01:00", epochEnv, now)
+	require.NoError(t, err)
+	assert.True(t, date.Equal(time.Date(2024, 1, 2, 2, 4, 5, 0, time.UTC)))
+
+	date, err = TagDate("86400", noEnv, now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(86400, 0).UTC(), date)
+
+	_, err = TagDate("yesterday", noEnv, now)
+	assert.Error(t, err)
+	_, err = TagDate("", func(string) string { return "soon" }, now)
+	assert.Error(t, err)
+}
+
+func TestRenderTagMessage(t *testing.T) {
+	_, commits, err := setupRepo()
+	require.NoError(t, err)
+	log, err := CommitLog(commits[2], commits[4])
+	require.NoError(t, err)
+
+	data := TagMessageData{
+		Tag:             "release/v1.1.0",
+		Version:         "1.1.0",
+		Prefix:          "release",
+		PreviousTag:     "release/v1.0.0",
+		PreviousVersion: "1.0.0",
+		Commits:         NewTagMessageCommits(log),
+	}
+	message, err := RenderTagMessage("{{.Prefix}} {{.Version}} (since {{.PreviousVersion}})\n{{range .Commits}}\n- {{.Subject}} ({{.ShortHash}}, {{.Author}}){{end}}", data)
+	require.NoError(t, err)
+	assert.Equal(t, "release 1.1.0 (since 1.0.0)\n\n- Fifth commit ("+commits[4].Hash.String()[:7]+", test)\n- Fourth commit ("+commits[3].Hash.String()[:7]+", test)", message)
+
+	_, err = RenderTagMessage("{{.Version", data)
+	assert.ErrorContains(t, err, "invalid message template")
+	_, err = RenderTagMessage("{{.Unknown}}", data)
+	assert.Error(t, err)
+}
+
+func TestCreateVersionTagTagger(t *testing.T) {
+	repo, commits, err := setupRepo()
+	require.NoError(t, err)
+	tagger := &object.Signature{Name: "Release Bot", Email: "release@example.com", When: time.Unix(1700000000, 0).UTC()}
+
+	tag, err := CreateVersionTagContext(context.Background(), repo.Storer, CreateOptions{
+		Revision: commits[4].Hash.String(), Version: semver.SemVer{Major: 3}, Annotation: &TagOptions{Message: "Release 3", Tagger: tagger},
+	})
+	require.NoError(t, err)
+	ref, err := repo.Tag(tag.Name)
+	require.NoError(t, err)
+	tagObject, err := repo.TagObject(ref.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, "Release Bot", tagObject.Tagger.Name)
+	assert.Equal(t, "release@example.com", tagObject.Tagger.Email)
+	assert.Equal(t, int64(1700000000), tagObject.Tagger.When.Unix())
+	assert.Equal(t, "Release 3\n", tagObject.Message)
+}
@@ -0,0 +1,272 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// The tag index caches the commit and commit time every tag points to, so that version tag lookups
+// need not read the tag and commit objects. It is stored in the git directory and keyed by a hash of
+// packed-refs: it is ignored once packed-refs changes, e.g. after a fetch or git gc. Each entry also
+// records the object the tag ref points to, so tags that were created, moved or deleted since the
+// index was written are resolved from the objects instead.
+const (
+	tagIndexPath   = "semver-git/tag-index"
+	tagIndexHeader = "# semver-git tag index v1 "
+	packedRefsPath = "packed-refs"
+)
+
+// tagIndexEntry is a tag ref peeled to the commit it points to.
+type tagIndexEntry struct {
+	ref    plumbing.Hash
+	commit plumbing.Hash
+	when   time.Time
+}
+
+// tagResolver peels tag refs to commits. Annotated tags are peeled with the "^<hash>" lines git
+// writes to packed-refs where possible, commits are read at most once, and entries of a valid tag
+// index are used without reading any objects.
+type tagResolver struct {
+	repo    *git.Repository
+	peeled  map[plumbing.Hash]plumbing.Hash
+	index   map[string]tagIndexEntry
+	commits map[plumbing.Hash]*object.Commit
+}
+
+// newTagResolver prepares a tagResolver for the tags whose full ref name starts with refPrefix.
+func newTagResolver(repo *git.Repository, refPrefix string) *tagResolver {
+	r := &tagResolver{
+		repo:    repo,
+		peeled:  make(map[plumbing.Hash]plumbing.Hash),
+		commits: make(map[plumbing.Hash]*object.Commit),
+	}
+	fs, ok := repoFilesystem(repo)
+	if !ok {
+		return r
+	}
+	// Both are only caches, so the objects are read if they cannot be.
+	packedRefs, err := readFile(fs, packedRefsPath)
+	if err != nil {
+		return r
+	}
+	r.peeled = parsePeeledRefs(packedRefs, refPrefix)
+	r.index, _ = readTagIndex(fs, packedRefsKey(packedRefs), refPrefix)
+	return r
+}
+
+// resolve returns the commit ref points to and the commit time.
+func (r *tagResolver) resolve(ref *plumbing.Reference) (plumbing.Hash, time.Time, error) {
+	if entry, ok := r.index[ref.Name().String()]; ok && entry.ref == ref.Hash() {
+		return entry.commit, entry.when, nil
+	}
+	hash := ref.Hash()
+	if peeled, ok := r.peeled[hash]; ok {
+		hash = peeled
+	}
+	commit, err := r.commit(hash)
+	if err != nil {
+		return plumbing.ZeroHash, time.Time{}, fmt.Errorf("failed to resolve tag '%s': %w", ref.Name().Short(), err)
+	}
+	return commit.Hash, commit.Committer.When, nil
+}
+
+// commit reads the commit hash is or, if hash is a tag object, points to.
+func (r *tagResolver) commit(hash plumbing.Hash) (*object.Commit, error) {
+	if commit, ok := r.commits[hash]; ok {
+		return commit, nil
+	}
+	obj, err := r.repo.Storer.EncodedObject(plumbing.AnyObject, hash)
+	if err != nil {
+		return nil, err
+	}
+	var commit *object.Commit
+	switch obj.Type() {
+	case plumbing.CommitObject:
+		commit, err = object.DecodeCommit(r.repo.Storer, obj)
+	case plumbing.TagObject:
+		var tag *object.Tag
+		if tag, err = object.DecodeTag(r.repo.Storer, obj); err == nil {
+			commit, err = r.commit(tag.Target)
+		}
+	default:
+		err = fmt.Errorf("%s is a %s, not a commit", hash, obj.Type())
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.commits[hash] = commit
+	return commit, nil
+}
+
+// UpdateTagIndex writes the tag index of an on-disk repository, which speeds up version tag lookups
+// in repositories with many tags, unless it is up to date. It returns the number of tags in the index;
+// tags that do not point to a commit are left out. Lookups use the index until packed-refs changes,
+// and look up tags created since the index was written without it.
+func UpdateTagIndex(repo *git.Repository) (int, error) {
+	fs, ok := repoFilesystem(repo)
+	if !ok {
+		return 0, errors.New("the tag index requires a repository on disk")
+	}
+	packedRefs, err := readFile(fs, packedRefsPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read packed refs: %w", err)
+	}
+	resolver := newTagResolver(repo, plumbing.NewTagReferenceName("").String())
+
+	tags, err := repo.Tags()
+	if err != nil {
+		return 0, fmt.Errorf("failed to retrieve tags: %w", err)
+	}
+	var index bytes.Buffer
+	index.WriteString(tagIndexHeader + packedRefsKey(packedRefs) + "\n")
+	count := 0
+	current := resolver.index != nil
+	err = tags.ForEach(func(ref *plumbing.Reference) error {
+		if entry, ok := resolver.index[ref.Name().String()]; !ok || entry.ref != ref.Hash() {
+			current = false
+		}
+		commit, when, err := resolver.resolve(ref)
+		if err != nil {
+			return nil
+		}
+		fmt.Fprintf(&index, "%s %s %s %d\n", ref.Name(), ref.Hash(), commit, when.Unix())
+		count++
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if current && count == len(resolver.index) {
+		return count, nil
+	}
+	if err := writeFileAtomic(fs, tagIndexPath, index.Bytes()); err != nil {
+		return 0, fmt.Errorf("failed to write tag index: %w", err)
+	}
+	return count, nil
+}
+
+// repoFilesystem returns the git directory of an on-disk repository.
+func repoFilesystem(repo *git.Repository) (billy.Filesystem, bool) {
+	storage, ok := repo.Storer.(*filesystem.Storage)
+	if !ok {
+		return nil, false
+	}
+	return storage.Filesystem(), true
+}
+
+// readFile returns the content of a file in fs, or nil if it does not exist.
+func readFile(fs billy.Filesystem, path string) ([]byte, error) {
+	f, err := fs.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return io.ReadAll(f)
+}
+
+// writeFileAtomic replaces a file in fs, so that concurrent readers see either the old or new content.
+func writeFileAtomic(fs billy.Filesystem, path string, data []byte) error {
+	if err := fs.MkdirAll(fs.Join(path, ".."), 0o755); err != nil {
+		return err
+	}
+	tmp, err := fs.TempFile(fs.Join(path, ".."), ".tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = fs.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = fs.Remove(tmp.Name())
+	}
+	return err
+}
+
+// packedRefsKey returns the key of the tag index for the given packed-refs content.
+func packedRefsKey(packedRefs []byte) string {
+	sum := sha256.Sum256(packedRefs)
+	return hex.EncodeToString(sum[:])
+}
+
+// parsePeeledRefs maps the annotated tags in packed-refs whose ref name starts with refPrefix to the
+// objects they point to.
+func parsePeeledRefs(packedRefs []byte, refPrefix string) map[plumbing.Hash]plumbing.Hash {
+	peeled := make(map[plumbing.Hash]plumbing.Hash)
+	var last plumbing.Hash
+	for len(packedRefs) > 0 {
+		var line []byte
+		line, packedRefs, _ = bytes.Cut(packedRefs, []byte("\n"))
+		switch {
+		case len(line) == 0 || line[0] == '#':
+		case line[0] == '^':
+			if !last.IsZero() {
+				peeled[last] = plumbing.NewHash(string(line[1:]))
+			}
+		default:
+			hash, name, _ := bytes.Cut(line, []byte(" "))
+			last = plumbing.ZeroHash
+			if bytes.HasPrefix(name, []byte(refPrefix)) {
+				last = plumbing.NewHash(string(hash))
+			}
+		}
+	}
+	return peeled
+}
+
+// readTagIndex reads the entries of the tag index for refs starting with refPrefix. It returns nil if
+// there is no index or it was written for other packed-refs.
+func readTagIndex(fs billy.Filesystem, key, refPrefix string) (map[string]tagIndexEntry, error) {
+	f, err := fs.Open(tagIndexPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() || scanner.Text() != tagIndexHeader+key {
+		return nil, scanner.Err()
+	}
+	index := make(map[string]tagIndexEntry)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, refPrefix) {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("invalid tag index entry '%s'", line)
+		}
+		seconds, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag index entry '%s'", line)
+		}
+		index[fields[0]] = tagIndexEntry{
+			ref:    plumbing.NewHash(fields[1]),
+			commit: plumbing.NewHash(fields[2]),
+			when:   time.Unix(seconds, 0),
+		}
+	}
+	return index, scanner.Err()
+}
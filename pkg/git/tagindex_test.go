@@ -0,0 +1,220 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Sizes of the repository generated by setupLargeRepo.
+const (
+	largeRepoCommits  = 1000
+	largeRepoPrefixes = 50
+	largeRepoTags     = 52000
+)
+
+// setupLargeRepo creates an on-disk repository with a linear history of largeRepoCommits commits and
+// largeRepoTags version tags, spread evenly over largeRepoPrefixes prefixes ("svc-NN/v1.X.Y") and the
+// commits. Every tenth tag is annotated. The tags are written to packed-refs the way git pack-refs
+// writes them, as after a clone or git gc. It returns the directory of the repository and its commits.
+func setupLargeRepo(tb testing.TB) (string, []*object.Commit) {
+	dir := tb.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(tb, err)
+
+	treeObj := repo.Storer.NewEncodedObject()
+	require.NoError(tb, (&object.Tree{}).Encode(treeObj))
+	treeHash, err := repo.Storer.SetEncodedObject(treeObj)
+	require.NoError(tb, err)
+
+	commits := make([]*object.Commit, largeRepoCommits)
+	var parents []plumbing.Hash
+	for i := range commits {
+		signature := object.Signature{Name: "test", Email: "test@example.com", When: time.Unix(int64(1000+i*60), 0)}
+		commit := &object.Commit{Author: signature, Committer: signature, Message: fmt.Sprintf("Commit %d", i), TreeHash: treeHash, ParentHashes: parents}
+		obj := repo.Storer.NewEncodedObject()
+		require.NoError(tb, commit.Encode(obj))
+		hash, err := repo.Storer.SetEncodedObject(obj)
+		require.NoError(tb, err)
+		commits[i], err = repo.CommitObject(hash)
+		require.NoError(tb, err)
+		parents = []plumbing.Hash{hash}
+	}
+	require.NoError(tb, repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("master"), parents[0])))
+
+	perPrefix := largeRepoTags / largeRepoPrefixes
+	var lines []string
+	for p := 0; p < largeRepoPrefixes; p++ {
+		for n := 0; n < perPrefix; n++ {
+			name := fmt.Sprintf("svc-%02d/v1.%d.%d", p, n/100, n%100)
+			commit := commits[n*largeRepoCommits/perPrefix]
+			line := commit.Hash.String() + " refs/tags/" + name
+			if n%10 == 0 {
+				tag := &object.Tag{Name: name, Tagger: commit.Committer, Message: "Version\n", TargetType: plumbing.CommitObject, Target: commit.Hash}
+				obj := repo.Storer.NewEncodedObject()
+				require.NoError(tb, tag.Encode(obj))
+				hash, err := repo.Storer.SetEncodedObject(obj)
+				require.NoError(tb, err)
+				line = hash.String() + " refs/tags/" + name + "\n^" + commit.Hash.String()
+			}
+			lines = append(lines, line)
+		}
+	}
+	sort.Strings(lines)
+	packedRefs := "# pack-refs with: peeled fully-peeled sorted \n" + strings.Join(lines, "\n") + "\n"
+	require.NoError(tb, os.WriteFile(filepath.Join(dir, ".git", "packed-refs"), []byte(packedRefs), 0o644))
+	return dir, commits
+}
+
+func TestFetchVersionTagLargeRepo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("generates a repository with many tags")
+	}
+	dir, commits := setupLargeRepo(t)
+	repo, err := git.PlainOpen(dir)
+	require.NoError(t, err)
+
+	check := func(t *testing.T, repo *git.Repository) {
+		// svc-07/v1.5.0 is annotated and the only tag of the prefix on commits[480].
+		tag, version, commit, err := fetchVersionTagWith(NewGoGitBackend(repo), commits[480], DefaultTagFormat, "svc-07", false)
+		require.NoError(t, err)
+		assert.Equal(t, "svc-07/v1.5.0", tag)
+		assert.Equal(t, "1.5.0", version.String())
+		assert.Equal(t, commits[480].Hash, commit.Hash)
+
+		tag, _, commit, err = fetchVersionTagWith(NewGoGitBackend(repo), commits[980], DefaultTagFormat, "svc-07", true)
+		require.NoError(t, err)
+		assert.Equal(t, "svc-07/v1.10.20", tag)
+		assert.Equal(t, commits[980].Hash, commit.Hash)
+
+		tag, _, _, err = fetchVersionTagWith(NewGoGitBackend(repo), commits[999], DefaultTagFormat, "svc-7", false)
+		require.NoError(t, err)
+		assert.Empty(t, tag)
+	}
+
+	t.Run("Without index", func(t *testing.T) {
+		check(t, repo)
+	})
+
+	t.Run("With index", func(t *testing.T) {
+		count, err := UpdateTagIndex(repo)
+		require.NoError(t, err)
+		assert.Equal(t, largeRepoTags, count)
+		check(t, repo)
+	})
+
+	t.Run("Tags created after the index", func(t *testing.T) {
+		_, err := repo.CreateTag("svc-07/v2.0.0", commits[480].Hash, nil)
+		require.NoError(t, err)
+		tag, _, commit, err := fetchVersionTagWith(NewGoGitBackend(repo), commits[480], DefaultTagFormat, "svc-07", false)
+		require.NoError(t, err)
+		assert.Equal(t, "svc-07/v2.0.0", tag)
+		assert.Equal(t, commits[480].Hash, commit.Hash)
+	})
+}
+
+func TestTagIndex(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+	w, err := repo.Worktree()
+	require.NoError(t, err)
+	var commits []*object.Commit
+	for i := 0; i < 3; i++ {
+		signature := &object.Signature{Name: "test", Email: "test@example.com", When: time.Unix(int64(100*(i+1)), 0)}
+		hash, err := w.Commit(fmt.Sprintf("Commit %d", i), &git.CommitOptions{Author: signature, Committer: signature, AllowEmptyCommits: true})
+		require.NoError(t, err)
+		commit, err := repo.CommitObject(hash)
+		require.NoError(t, err)
+		commits = append(commits, commit)
+	}
+	_, err = repo.CreateTag("v1.0.0", commits[0].Hash, nil)
+	require.NoError(t, err)
+	_, err = repo.CreateTag("v1.1.0", commits[1].Hash, &git.CreateTagOptions{Tagger: &commits[1].Committer, Message: "Version 1.1.0"})
+	require.NoError(t, err)
+	require.NoError(t, repo.Storer.PackRefs())
+
+	count, err := UpdateTagIndex(repo)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	index, err := os.ReadFile(filepath.Join(dir, ".git", tagIndexPath))
+	require.NoError(t, err)
+	assert.Contains(t, string(index), fmt.Sprintf("refs/tags/v1.0.0 %s %s 100\n", commits[0].Hash, commits[0].Hash))
+
+	tag, _, commit, err := FetchVersionTag(repo, commits[2], "", false)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.1.0", tag)
+	assert.Equal(t, commits[1].Hash, commit.Hash)
+
+	// A loose ref moving a packed tag is resolved from the objects rather than the index.
+	assert.Len(t, newTagResolver(repo, "refs/tags/").index, 2)
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewTagReferenceName("v1.1.0"), commits[2].Hash)))
+	tag, _, commit, err = FetchVersionTag(repo, commits[2], "", true)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.1.0", tag)
+	assert.Equal(t, commits[2].Hash, commit.Hash)
+
+	// The index is ignored once packed-refs changes.
+	require.NoError(t, repo.Storer.PackRefs())
+	r := newTagResolver(repo, "refs/tags/")
+	assert.Nil(t, r.index)
+}
+
+func TestParsePeeledRefs(t *testing.T) {
+	packedRefs := "# pack-refs with: peeled fully-peeled sorted \n" +
+		"1111111111111111111111111111111111111111 refs/heads/master\n" +
+		"2222222222222222222222222222222222222222 refs/tags/v1.0.0\n" +
+		"^3333333333333333333333333333333333333333\n" +
+		"4444444444444444444444444444444444444444 refs/tags/v1.1.0\n"
+	peeled := parsePeeledRefs([]byte(packedRefs), "refs/tags/")
+	assert.Equal(t, map[plumbing.Hash]plumbing.Hash{
+		plumbing.NewHash("2222222222222222222222222222222222222222"): plumbing.NewHash("3333333333333333333333333333333333333333"),
+	}, peeled)
+	assert.Empty(t, parsePeeledRefs([]byte(packedRefs), "refs/tags/release/"))
+}
+
+func BenchmarkFetchVersionTag(b *testing.B) {
+	dir, commits := setupLargeRepo(b)
+	target := commits[len(commits)-1]
+
+	lookup := func(b *testing.B, prefix string) {
+		for i := 0; i < b.N; i++ {
+			// Each lookup opens the repository, as a semver-git invocation does.
+			repo, err := git.PlainOpen(dir)
+			require.NoError(b, err)
+			tag, _, _, err := fetchVersionTagWith(NewGoGitBackend(repo), target, DefaultTagFormat, prefix, false)
+			require.NoError(b, err)
+			require.NotEmpty(b, tag)
+		}
+	}
+
+	b.Run("Prefix", func(b *testing.B) { lookup(b, "svc-07") })
+
+	repo, err := git.PlainOpen(dir)
+	require.NoError(b, err)
+	_, err = UpdateTagIndex(repo)
+	require.NoError(b, err)
+
+	b.Run("PrefixWithIndex", func(b *testing.B) { lookup(b, "svc-07") })
+}
+
+func BenchmarkUpdateTagIndex(b *testing.B) {
+	dir, _ := setupLargeRepo(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		repo, err := git.PlainOpen(dir)
+		require.NoError(b, err)
+		_, err = UpdateTagIndex(repo)
+		require.NoError(b, err)
+	}
+}
@@ -0,0 +1,258 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/coreeng/semver-utils/pkg/semver"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage"
+)
+
+// VersionTag is a version tag found by FetchVersionTagContext or created by CreateVersionTagContext.
+type VersionTag struct {
+	// Name is the name of the tag, e.g. "services/api/v1.2.3".
+	Name string
+	// Version is the version of the tag.
+	Version semver.SemVer
+	// Commit is the commit the tag points to.
+	Commit *object.Commit
+	// Signer is the trusted key that signed the tag if FetchOptions.Verify was set, otherwise nil.
+	Signer *openpgp.Entity
+}
+
+// FetchOptions configures FetchVersionTagContext. The zero value finds the latest version tag without
+// a prefix in the history of HEAD.
+type FetchOptions struct {
+	// Revision is the commit the search starts from, e.g. a branch, tag or commit hash; HEAD if empty.
+	Revision string
+	// Prefix is the prefix of the version tags, empty for tags without a prefix.
+	Prefix string
+	// Format is the layout of the tag names; the zero value is DefaultTagFormat.
+	Format TagFormat
+	// ExactCommit only considers tags pointing to the commit itself. Otherwise the most recent tag in
+	// its history is selected.
+	ExactCommit bool
+	// Filter, if not nil, only considers the tags it returns true for, e.g. to skip prereleases.
+	Filter func(tagName string, version semver.SemVer) bool
+	// Verify, if not nil, only considers tags signed by one of its trusted keys, and the signer of the
+	// tag is returned in VersionTag.Signer.
+	Verify *SignatureVerification
+	// Backend, if not nil, is the Backend the repository is read with, e.g. one returned by NewBackend.
+	// Otherwise the storage passed to FetchVersionTagContext is read with go-git.
+	Backend Backend
+}
+
+// CreateOptions configures CreateVersionTagContext.
+type CreateOptions struct {
+	// Revision is the commit to tag, e.g. a branch, tag or commit hash; HEAD if empty.
+	Revision string
+	// Prefix is the prefix of the tag, empty for a tag without a prefix.
+	Prefix string
+	// Format is the layout of the tag name; the zero value is DefaultTagFormat.
+	Format TagFormat
+	// Version is the version of the tag.
+	Version semver.SemVer
+	// Annotation, if not nil, makes the tag an annotated tag. Otherwise a lightweight tag is created.
+	Annotation *TagOptions
+	// Backend, if not nil, is the Backend the tag is created with, e.g. one returned by NewBackend.
+	// Otherwise the tag is written to the storage passed to CreateVersionTagContext with go-git.
+	Backend Backend
+}
+
+// FetchVersionTagContext searches the repository in s, any go-git storage such as an in-memory
+// repository or the Storer of a git.Repository, for the version tag described by opts. s is not used
+// if opts.Backend is set. It returns ErrNoVersionTag if no tag matches, and the error of ctx if ctx is
+// done before the search completes.
+func FetchVersionTagContext(ctx context.Context, s storage.Storer, opts FetchOptions) (VersionTag, error) {
+	b, closeBackend, err := optionsBackend(s, opts.Backend)
+	if err != nil {
+		return VersionTag{}, err
+	}
+	defer closeBackend()
+
+	targetCommit, err := resolveCommit(ctx, b, opts.Revision)
+	if err != nil {
+		return VersionTag{}, err
+	}
+	return fetchVersionTag(ctx, b, targetCommit, opts)
+}
+
+// CreateVersionTagContext creates the version tag described by opts in the repository in s, any
+// go-git storage such as an in-memory repository or the Storer of a git.Repository. s is not used if
+// opts.Backend is set. It returns ErrTagExists if a tag of the same name exists, and the error of ctx
+// if ctx is done before the tag is created.
+func CreateVersionTagContext(ctx context.Context, s storage.Storer, opts CreateOptions) (VersionTag, error) {
+	b, closeBackend, err := optionsBackend(s, opts.Backend)
+	if err != nil {
+		return VersionTag{}, err
+	}
+	defer closeBackend()
+
+	targetCommit, err := resolveCommit(ctx, b, opts.Revision)
+	if err != nil {
+		return VersionTag{}, err
+	}
+	return createVersionTag(ctx, b, targetCommit, opts)
+}
+
+// optionsBackend returns b if it is not nil, which the caller closes, and otherwise the go-git
+// Backend for the repository in s. It fails if both are nil. The returned function closes the
+// backend opened here.
+func optionsBackend(s storage.Storer, b Backend) (Backend, func(), error) {
+	if b != nil {
+		return b, func() {}, nil
+	}
+	if s == nil {
+		return nil, nil, errors.New("a storer or backend is required")
+	}
+	repo, err := git.Open(s, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	b = NewGoGitBackend(repo)
+	return b, func() { _ = b.Close() }, nil
+}
+
+// resolveCommit returns the commit of revision, or of HEAD if revision is empty.
+func resolveCommit(ctx context.Context, b Backend, revision string) (*object.Commit, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if revision == "" {
+		revision = "HEAD"
+	}
+	return fetchCommitObject(b, revision)
+}
+
+// fetchVersionTag implements FetchVersionTagContext for targetCommit, verifying the signatures of
+// the tags if opts.Verify is set.
+func fetchVersionTag(ctx context.Context, b Backend, targetCommit *object.Commit, opts FetchOptions) (VersionTag, error) {
+	if opts.Verify == nil {
+		return findVersionTag(ctx, b, targetCommit, opts, nil)
+	}
+	verify := *opts.Verify
+
+	if verify.FailOnUnverified {
+		tag, err := findVersionTag(ctx, b, targetCommit, opts, nil)
+		if err != nil {
+			return VersionTag{}, err
+		}
+		tag.Signer, err = verifyTagSignature(b, tag.Name, verify.TrustedKeys)
+		if err != nil {
+			return VersionTag{}, err
+		}
+		return tag, nil
+	}
+
+	signers := make(map[string]*openpgp.Entity)
+	tag, err := findVersionTag(ctx, b, targetCommit, opts, func(ref TagRef) bool {
+		// The ref is already known, so only the tag object is read.
+		info, err := readTagInfo(b, ref.Name, ref.Hash)
+		if err != nil {
+			return false
+		}
+		signer, err := verifyTagInfo(info, verify.TrustedKeys)
+		signers[ref.Name] = signer
+		return err == nil
+	})
+	if err != nil {
+		return VersionTag{}, err
+	}
+	tag.Signer = signers[tag.Name]
+	return tag, nil
+}
+
+// findVersionTag searches the tags of b for the version tag of targetCommit described by opts. If
+// accept is not nil, a tag is only selected if accept returns true for it; it is only called for tags
+// that would be selected otherwise.
+func findVersionTag(ctx context.Context, b Backend, targetCommit *object.Commit, opts FetchOptions, accept func(ref TagRef) bool) (VersionTag, error) {
+	matcher, err := opts.Format.matcher(opts.Prefix)
+	if err != nil {
+		return VersionTag{}, err
+	}
+	literal, err := opts.Format.literalPrefix(opts.Prefix)
+	if err != nil {
+		return VersionTag{}, err
+	}
+
+	// Only refs that can match are returned and parsed.
+	tags, err := b.TagRefs(plumbing.NewTagReferenceName(literal).String())
+	if err != nil {
+		return VersionTag{}, err
+	}
+
+	var found *TagRef
+	var foundVersion semver.SemVer
+	targetTime := targetCommit.Committer.When
+
+	for i := range tags {
+		if err := ctx.Err(); err != nil {
+			return VersionTag{}, err
+		}
+		candidate := &tags[i]
+		_, candidateVersion, ok := matchLayout(matcher, candidate.Name)
+		if !ok || (opts.Filter != nil && !opts.Filter(candidate.Name, candidateVersion)) {
+			continue
+		}
+
+		var better bool
+		if opts.ExactCommit {
+			if candidate.Commit != targetCommit.Hash {
+				continue
+			}
+			better = found == nil || candidateVersion.Compare(foundVersion) > 0
+		} else {
+			if candidate.When.After(targetTime) {
+				continue
+			}
+			// Several tags on the same commit prefer the higher version.
+			better = found == nil || candidate.When.After(found.When) ||
+				(candidate.Commit == found.Commit && candidateVersion.Compare(foundVersion) > 0)
+		}
+		if better && (accept == nil || accept(*candidate)) {
+			found = candidate
+			foundVersion = candidateVersion
+		}
+	}
+	if found == nil {
+		return VersionTag{}, ErrNoVersionTag
+	}
+
+	foundCommit, err := b.Commit(found.Commit)
+	if err != nil {
+		return VersionTag{}, fmt.Errorf("failed to resolve tag '%s': %w", found.Name, err)
+	}
+	return VersionTag{Name: found.Name, Version: foundVersion, Commit: foundCommit}, nil
+}
+
+// createVersionTag implements CreateVersionTagContext for targetCommit.
+func createVersionTag(ctx context.Context, b Backend, targetCommit *object.Commit, opts CreateOptions) (VersionTag, error) {
+	newTagName, err := opts.Format.Format(opts.Prefix, opts.Version)
+	if err != nil {
+		return VersionTag{}, err
+	}
+
+	var tagOpts *git.CreateTagOptions
+	if opts.Annotation != nil {
+		tagOpts = &git.CreateTagOptions{Message: opts.Annotation.Message, Tagger: opts.Annotation.Tagger, SignKey: opts.Annotation.SignKey}
+		if tagOpts.Message == "" {
+			tagOpts.Message = fmt.Sprintf("Version %s", opts.Version.String())
+		}
+		if tagOpts.Tagger == nil {
+			tagOpts.Tagger = &targetCommit.Committer
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return VersionTag{}, err
+	}
+	if err := b.CreateTag(newTagName, targetCommit.Hash, tagOpts); err != nil {
+		return VersionTag{}, fmt.Errorf("failed to create tag: %w", err)
+	}
+
+	return VersionTag{Name: newTagName, Version: opts.Version, Commit: targetCommit}, nil
+}
@@ -0,0 +1,110 @@
+package git
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coreeng/semver-utils/pkg/semver"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchVersionTagContext(t *testing.T) {
+	repo, commits, err := setupRepo()
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	t.Run("Latest tag from HEAD", func(t *testing.T) {
+		tag, err := FetchVersionTagContext(ctx, repo.Storer, FetchOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, "v1.4.0-alpha.1", tag.Name)
+		assert.Equal(t, "1.4.0-alpha.1", tag.Version.String())
+		assert.Equal(t, commits[4].Hash, tag.Commit.Hash)
+		assert.Nil(t, tag.Signer)
+	})
+
+	t.Run("Revision and prefix", func(t *testing.T) {
+		tag, err := FetchVersionTagContext(ctx, repo.Storer, FetchOptions{Revision: commits[3].Hash.String(), Prefix: "release"})
+		require.NoError(t, err)
+		assert.Equal(t, "release/v1.0.0", tag.Name)
+		assert.Equal(t, commits[3].Hash, tag.Commit.Hash)
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		tag, err := FetchVersionTagContext(ctx, repo.Storer, FetchOptions{Filter: func(_ string, version semver.SemVer) bool {
+			return version.PreRelease == ""
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, "v1.0.0", tag.Name)
+	})
+
+	t.Run("No version tag", func(t *testing.T) {
+		_, err := FetchVersionTagContext(ctx, repo.Storer, FetchOptions{Revision: commits[1].Hash.String()})
+		assert.ErrorIs(t, err, ErrNoVersionTag)
+		_, err = FetchVersionTagContext(ctx, repo.Storer, FetchOptions{ExactCommit: true, Revision: commits[1].Hash.String()})
+		assert.ErrorIs(t, err, ErrNoVersionTag)
+	})
+
+	t.Run("Cancelled context", func(t *testing.T) {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := FetchVersionTagContext(cancelled, repo.Storer, FetchOptions{})
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("Backend", func(t *testing.T) {
+		onDisk, _, err := setupRepoOnDisk(t.TempDir())
+		require.NoError(t, err)
+		b, err := NewBackend(onDisk, BackendGit)
+		require.NoError(t, err)
+		defer func() { assert.NoError(t, b.Close()) }()
+		tag, err := FetchVersionTagContext(ctx, nil, FetchOptions{Prefix: "release", Backend: b})
+		require.NoError(t, err)
+		assert.Equal(t, "release/v1.0.0", tag.Name)
+	})
+
+	t.Run("Empty storage", func(t *testing.T) {
+		_, err := FetchVersionTagContext(ctx, memory.NewStorage(), FetchOptions{})
+		assert.ErrorIs(t, err, git.ErrRepositoryNotExists)
+	})
+
+	t.Run("No storage or backend", func(t *testing.T) {
+		_, err := FetchVersionTagContext(ctx, nil, FetchOptions{})
+		assert.ErrorContains(t, err, "a storer or backend is required")
+		_, err = CreateVersionTagContext(ctx, nil, CreateOptions{})
+		assert.ErrorContains(t, err, "a storer or backend is required")
+	})
+}
+
+func TestCreateVersionTagContext(t *testing.T) {
+	repo, commits, err := setupRepo()
+	require.NoError(t, err)
+	ctx := context.Background()
+	version, err := semver.Parse("2.0.0")
+	require.NoError(t, err)
+
+	tag, err := CreateVersionTagContext(ctx, repo.Storer, CreateOptions{Prefix: "api", Version: version, Annotation: &TagOptions{}})
+	require.NoError(t, err)
+	assert.Equal(t, "api/v2.0.0", tag.Name)
+	assert.Equal(t, commits[4].Hash, tag.Commit.Hash)
+	message, annotated, err := TagMessage(repo, tag.Name)
+	require.NoError(t, err)
+	assert.True(t, annotated)
+	assert.Equal(t, "Version 2.0.0\n", message)
+
+	found, err := FetchVersionTagContext(ctx, repo.Storer, FetchOptions{Prefix: "api", ExactCommit: true})
+	require.NoError(t, err)
+	assert.Equal(t, tag.Name, found.Name)
+
+	_, err = CreateVersionTagContext(ctx, repo.Storer, CreateOptions{Revision: commits[2].Hash.String(), Prefix: "api", Version: version})
+	assert.ErrorIs(t, err, ErrTagExists)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = CreateVersionTagContext(cancelled, repo.Storer, CreateOptions{Prefix: "web", Version: version})
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.Tag("web/v2.0.0")
+	assert.ErrorIs(t, err, git.ErrTagNotFound)
+}
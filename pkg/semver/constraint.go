@@ -0,0 +1,81 @@
+package semver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Constraint is a set of version comparisons which must all hold, such as ">=1.2.0, <2.0.0".
+type Constraint struct {
+	comparisons []comparison
+}
+
+type comparison struct {
+	operator string
+	version  SemVer
+}
+
+// constraintOperators are checked in order, so two-character operators come first.
+var constraintOperators = []string{">=", "<=", "!=", "==", ">", "<", "="}
+
+// ParseConstraint parses a comma or space separated list of comparisons. Each comparison is one of
+// the operators =, ==, !=, >, >=, < and <= followed by a full Semantic Version; without an operator
+// the version must match exactly. Versions are compared by precedence, see Compare.
+func ParseConstraint(s string) (Constraint, error) {
+	var c Constraint
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		operator := "="
+		for _, op := range constraintOperators {
+			if strings.HasPrefix(field, op) {
+				operator = op
+				field = strings.TrimPrefix(field, op)
+				break
+			}
+		}
+		// Allow whitespace between the operator and the version, e.g. ">= 1.2.0".
+		if field == "" && i+1 < len(fields) {
+			i++
+			field = fields[i]
+		}
+		if operator == "==" {
+			operator = "="
+		}
+		version, err := Parse(field)
+		if err != nil {
+			return Constraint{}, fmt.Errorf("invalid constraint '%s': %w", s, err)
+		}
+		c.comparisons = append(c.comparisons, comparison{operator: operator, version: version})
+	}
+	if len(c.comparisons) == 0 {
+		return Constraint{}, fmt.Errorf("invalid constraint '%s': no comparison found", s)
+	}
+	return c, nil
+}
+
+// Check reports whether v satisfies every comparison of the constraint.
+func (c Constraint) Check(v SemVer) bool {
+	for _, cmp := range c.comparisons {
+		result := v.Compare(cmp.version)
+		var ok bool
+		switch cmp.operator {
+		case "=":
+			ok = result == 0
+		case "!=":
+			ok = result != 0
+		case ">":
+			ok = result > 0
+		case ">=":
+			ok = result >= 0
+		case "<":
+			ok = result < 0
+		case "<=":
+			ok = result <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
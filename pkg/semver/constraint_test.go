@@ -0,0 +1,42 @@
+package semver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		expected   bool
+	}{
+		{"1.2.3", "1.2.3", true},
+		{"=1.2.3", "1.2.4", false},
+		{"==v1.2.3", "1.2.3+build.1", true},
+		{"!=1.2.3", "1.2.4", true},
+		{">1.2.3", "1.2.3", false},
+		{">=1.2.3", "1.2.3", true},
+		{"<2.0.0", "2.0.0-rc.1", true},
+		{"<=2.0.0", "2.0.0", true},
+		{">=1.2.0, <2.0.0", "1.9.9", true},
+		{">=1.2.0, <2.0.0", "2.0.0", false},
+		{">= 1.2.0 < 2.0.0", "1.1.0", false},
+		{">=0.0.0-0", "0.1.0-alpha", true},
+	}
+
+	for _, test := range tests {
+		c, err := ParseConstraint(test.constraint)
+		require.NoError(t, err, "ParseConstraint(%q)", test.constraint)
+		v, err := Parse(test.version)
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, c.Check(v), "%q.Check(%q)", test.constraint, test.version)
+	}
+
+	for _, invalid := range []string{"", ",", ">=", ">=1.2", "~1.2.3", "1.2.3 ||"} {
+		_, err := ParseConstraint(invalid)
+		assert.Error(t, err, "ParseConstraint(%q)", invalid)
+	}
+}
@@ -115,6 +115,49 @@ func (v SemVer) SetBuildMetadata(buildMetadata BuildMetadata) (SemVer, error) {
 	return SemVer{Major: v.Major, Minor: v.Minor, Patch: v.Patch, PreRelease: v.PreRelease, BuildMetadata: buildMetadata}, nil
 }
 
+// Release returns the version without its PreRelease and BuildMetadata, e.g. 1.2.3 for 1.2.3-rc.1+build.5.
+func (v SemVer) Release() SemVer {
+	return SemVer{Major: v.Major, Minor: v.Minor, Patch: v.Patch}
+}
+
+// PreReleaseCounter returns N if the PreRelease of v is label followed by a numeric identifier N,
+// e.g. 3 for 1.2.3-rc.3 and label "rc".
+func (v SemVer) PreReleaseCounter(label PreRelease) (int, bool) {
+	counter, ok := strings.CutPrefix(string(v.PreRelease), string(label)+".")
+	if !ok || counter == "" || !isNumeric(counter) || (len(counter) > 1 && counter[0] == '0') {
+		return 0, false
+	}
+	n, err := strconv.Atoi(counter)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// SanitizeIdentifier converts an arbitrary string, such as a branch name, into a single valid
+// PreRelease identifier: every run of characters other than ASCII letters and digits becomes a single '-',
+// leading and trailing '-' are removed, and purely numeric results are prefixed with 'x' to avoid
+// leading zeros. For example "feature/Login_Page" becomes "feature-Login-Page".
+func SanitizeIdentifier(s string) string {
+	var sb strings.Builder
+	lastDash := false
+	for _, c := range s {
+		isValid := (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if isValid {
+			sb.WriteRune(c)
+			lastDash = false
+		} else if !lastDash {
+			sb.WriteRune('-')
+			lastDash = true
+		}
+	}
+	result := strings.Trim(sb.String(), "-")
+	if result != "" && isNumeric(result) {
+		result = "x" + result
+	}
+	return result
+}
+
 func (v SemVer) String() string {
 	result := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
 	if v.PreRelease != "" {
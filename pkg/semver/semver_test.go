@@ -181,6 +181,64 @@ func TestSetPreRelease(t *testing.T) {
 	}
 }
 
+// TestRelease tests that Release strips the pre-release and build metadata
+func TestRelease(t *testing.T) {
+	v := SemVer{Major: 1, Minor: 2, Patch: 3, PreRelease: "rc.1", BuildMetadata: "build.5"}
+	assert.Equal(t, SemVer{Major: 1, Minor: 2, Patch: 3}, v.Release())
+}
+
+// TestPreReleaseCounter tests the PreReleaseCounter function of the SemVer struct
+func TestPreReleaseCounter(t *testing.T) {
+	tests := []struct {
+		preRelease PreRelease
+		label      PreRelease
+		expected   int
+		ok         bool
+	}{
+		{"rc.1", "rc", 1, true},
+		{"rc.12", "rc", 12, true},
+		{"feature-login.3", "feature-login", 3, true},
+		{"rc", "rc", 0, false},
+		{"rc.x", "rc", 0, false},
+		{"rc.01", "rc", 0, false},
+		{"rc.1.2", "rc", 0, false},
+		{"beta.1", "rc", 0, false},
+		{"rc2.1", "rc", 0, false},
+	}
+
+	for _, test := range tests {
+		n, ok := SemVer{Major: 1, PreRelease: test.preRelease}.PreReleaseCounter(test.label)
+		assert.Equal(t, test.ok, ok, "PreReleaseCounter(%q, %q)", test.preRelease, test.label)
+		assert.Equal(t, test.expected, n, "PreReleaseCounter(%q, %q)", test.preRelease, test.label)
+	}
+}
+
+// TestSanitizeIdentifier tests that arbitrary strings become valid pre-release identifiers
+func TestSanitizeIdentifier(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"main", "main"},
+		{"feature/login", "feature-login"},
+		{"feature/Login_Page", "feature-Login-Page"},
+		{"fix//double--dash", "fix-double-dash"},
+		{"/leading/and/trailing/", "leading-and-trailing"},
+		{"release/1.2", "release-1-2"},
+		{"0123", "x0123"},
+		{"@@@", ""},
+	}
+
+	for _, test := range tests {
+		result := SanitizeIdentifier(test.input)
+		assert.Equal(t, test.expected, result, "SanitizeIdentifier(%q)", test.input)
+		if result != "" {
+			_, err := SemVer{Major: 1}.SetPreRelease(PreRelease(result))
+			assert.NoError(t, err, "SanitizeIdentifier(%q) must produce a valid pre-release", test.input)
+		}
+	}
+}
+
 // TestSetBuildMetadata tests the SetBuildMetadata function of the SemVer struct
 func TestSetBuildMetadata(t *testing.T) {
 	tests := []struct {